@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAdmin_Suspend(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	adminRole := &store.Role{ID: 3, Name: store.AdminRole, Level: 3, Permissions: []string{store.PermUsersSuspend}}
+	userRole := &store.Role{ID: 1, Name: store.UserRole, Level: 1}
+	admin := &store.User{ID: 1, Username: "admin", Role: *adminRole}
+	user := &store.User{ID: 2, Username: "john_doe", Role: *userRole}
+
+	asAdmin := authRequests(app, mocks, admin)
+	asUser := authRequests(app, mocks, user)
+
+	t.Run("Should_allow_only_admins",
+		func(t *testing.T) {
+			req := asUser(t, http.MethodPut, "/v1/admin/users/1/suspend", `{"reason": "spam"}`)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(1)).Return(userRole, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_suspend_for_duration",
+		func(t *testing.T) {
+			req := asAdmin(t, http.MethodPut, "/v1/admin/users/2/suspend", `{"reason": "spam", "duration": "72h"}`)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(3)).Return(adminRole, nil)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(user, nil)
+			mocks.Suspend.EXPECT().Suspend(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, s *store.Suspension) error {
+					if s.UserID != 2 || s.ExpiresAt == nil || *s.AdminID != 1 {
+						t.Errorf("unexpected suspension %+v", s)
+					}
+					return nil
+				})
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusCreated, t)
+		})
+
+	t.Run("Should_reject_invalid_duration",
+		func(t *testing.T) {
+			req := asAdmin(t, http.MethodPut, "/v1/admin/users/2/suspend", `{"reason": "spam", "duration": "-1h"}`)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(3)).Return(adminRole, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_reject_tokens_of_suspended_user",
+		func(t *testing.T) {
+			suspended := *user
+			suspended.Suspension = &store.Suspension{UserID: 2, Reason: "spam"}
+			req := authRequests(app, mocks, &suspended)(t, http.MethodGet, "/v1/users/feed", "")
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_accept_tokens_after_suspension_expired",
+		func(t *testing.T) {
+			expired := *user
+			expiresAt := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+			expired.Suspension = &store.Suspension{UserID: 2, Reason: "spam", ExpiresAt: &expiresAt}
+			req := authRequests(app, mocks, &expired)(t, http.MethodGet, "/v1/users/suggestions", "")
+			mocks.Followers.EXPECT().GetSuggestions(gomock.Any(), int64(2), maxSuggestions).Return([]store.Suggestion{}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+		})
+}
+
+func TestAdmin_Roles(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	adminRole := &store.Role{
+		ID:          3,
+		Name:        store.AdminRole,
+		Level:       3,
+		Permissions: []string{store.PermRolesManage, store.PermPostsUpdateAny, store.PermCommentsDeleteAny},
+	}
+	admin := &store.User{ID: 1, Username: "admin", Role: *adminRole}
+
+	asAdmin := authRequests(app, mocks, admin)
+	newRequest := func(t *testing.T, method, url, body string) *http.Request {
+		req := asAdmin(t, method, url, body)
+		mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(3)).Return(adminRole, nil)
+
+		return req
+	}
+
+	t.Run("Should_assign_role",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/admin/users/2/role", `{"role": "moderator"}`)
+			mocks.Roles.EXPECT().GetByName(gomock.Any(), store.ModeratorRole).
+				Return(&store.Role{Name: store.ModeratorRole, Level: 2}, nil)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).
+				Return(&store.User{ID: 2, Role: store.Role{Name: store.UserRole, Level: 1}}, nil)
+			mocks.Roles.EXPECT().Assign(gomock.Any(), int64(2), store.ModeratorRole, int64(1)).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNoContent, t)
+		})
+
+	t.Run("Should_not_change_role_of_other_admin",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/admin/users/2/role", `{"role": "user"}`)
+			mocks.Roles.EXPECT().GetByName(gomock.Any(), store.UserRole).
+				Return(&store.Role{Name: store.UserRole, Level: 1}, nil)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).
+				Return(&store.User{ID: 2, Role: *adminRole}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_not_create_role_above_own_level",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/admin/roles", `{"name": "owner", "level": 10}`)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_create_role",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/admin/roles",
+				`{"name": "editor", "level": 2, "permissions": ["posts.update.any"]}`)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(3)).Return(adminRole, nil)
+			mocks.Roles.EXPECT().Create(gomock.Any(), &store.Role{
+				Name:        "editor",
+				Level:       2,
+				Permissions: []string{store.PermPostsUpdateAny},
+			}, int64(1)).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusCreated, t)
+		})
+
+	t.Run("Should_not_create_role_with_taken_name",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/admin/roles",
+				`{"name": "moderator", "level": 2, "permissions": ["posts.update.any"]}`)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(3)).Return(adminRole, nil)
+			mocks.Roles.EXPECT().Create(gomock.Any(), gomock.Any(), int64(1)).Return(store.ErrConflict)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusConflict, t)
+		})
+
+	t.Run("Should_not_grant_missing_permission",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/admin/roles",
+				`{"name": "editor", "level": 2, "permissions": ["users.suspend"]}`)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(3)).Return(adminRole, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_not_accept_unknown_permission",
+		func(t *testing.T) {
+			req := asAdmin(t, http.MethodPut, "/v1/admin/roles/moderator/permissions",
+				`{"permissions": ["posts.read.any"]}`)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(3)).Return(adminRole, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_replace_permissions_and_reset_cache",
+		func(t *testing.T) {
+			app.config.redis.enabled = true
+			defer func() { app.config.redis.enabled = false }()
+
+			req := asAdmin(t, http.MethodPut, "/v1/admin/roles/moderator/permissions",
+				`{"permissions": ["posts.update.any"]}`)
+			mocks.RoleCache.EXPECT().Get(gomock.Any(), int64(3)).Return(adminRole, nil).Times(2)
+			mocks.Roles.EXPECT().GetByName(gomock.Any(), store.ModeratorRole).
+				Return(&store.Role{ID: 2, Name: store.ModeratorRole, Level: 2}, nil)
+			mocks.Roles.EXPECT().SetPermissions(gomock.Any(), &store.Role{
+				ID:          2,
+				Name:        store.ModeratorRole,
+				Level:       2,
+				Permissions: []string{store.PermPostsUpdateAny},
+			}, int64(1)).Return(nil)
+			mocks.RoleCache.EXPECT().Delete(gomock.Any(), int64(2)).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+		})
+}
+
+func TestAdmin_Impersonate(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	adminRole := &store.Role{ID: 3, Name: store.AdminRole, Level: 3, Permissions: []string{store.PermUsersImpersonate}}
+	admin := &store.User{ID: 1, Username: "admin", Role: *adminRole}
+	user := &store.User{ID: 2, Username: "john_doe", Role: store.Role{ID: 1, Name: store.UserRole, Level: 1}}
+
+	newImpersonatedRequest := func(t *testing.T, method, url string) *http.Request {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			t.Fatal("Request not created: ", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		mocks.Auth.EXPECT().ValidateToken(testToken).Return(&jwt.Token{
+			Valid: true,
+			Claims: jwt.MapClaims{
+				"sub": float64(2),
+				"act": map[string]any{"sub": float64(1), "sid": "admin-session"},
+			},
+		}, nil)
+		mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(user, nil)
+
+		return req
+	}
+	//Admin is still logged in
+	expectActor := func() {
+		mocks.Sessions.EXPECT().GetByUserID(gomock.Any(), int64(1)).Return([]store.Session{{ID: "admin-session"}}, nil)
+		mocks.Users.EXPECT().GetByID(gomock.Any(), int64(1)).Return(admin, nil)
+		mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(3)).Return(adminRole, nil)
+	}
+
+	t.Run("Should_issue_token_with_actor",
+		func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/admin/users/2/impersonate", nil)
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			mocks.Auth.EXPECT().ValidateToken(testToken).Return(&jwt.Token{
+				Valid:  true,
+				Claims: jwt.MapClaims{"sub": float64(1), "jti": "admin-session"},
+			}, nil)
+			mocks.Sessions.EXPECT().GetByUserID(gomock.Any(), int64(1)).Return([]store.Session{{ID: "admin-session"}}, nil)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(1)).Return(admin, nil)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(3)).Return(adminRole, nil)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(user, nil)
+			mocks.Auth.EXPECT().GenerateToken(gomock.Any()).DoAndReturn(
+				func(claims jwt.Claims) (string, error) {
+					mc := claims.(jwt.MapClaims)
+					act, _ := mc["act"].(map[string]any)
+					if mc["sub"] != int64(2) || act["sub"] != int64(1) || act["sid"] != "admin-session" {
+						t.Errorf("unexpected claims %v", mc)
+					}
+					return "impersonation-token", nil
+				})
+			mocks.Audit.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusCreated, t)
+		})
+
+	t.Run("Should_not_issue_token_without_session",
+		func(t *testing.T) {
+			req := authRequests(app, mocks, admin)(t, http.MethodPost, "/v1/admin/users/2/impersonate", "")
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(3)).Return(adminRole, nil)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(user, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_end_when_admin_logs_out",
+		func(t *testing.T) {
+			req := newImpersonatedRequest(t, http.MethodGet, "/v1/users/feed")
+			mocks.Sessions.EXPECT().GetByUserID(gomock.Any(), int64(1)).Return([]store.Session{}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusUnauthorized, t)
+		})
+
+	t.Run("Should_log_errors_with_actor",
+		func(t *testing.T) {
+			core, logs := observer.New(zap.WarnLevel)
+			defer func(logger *zap.SugaredLogger) { app.logger = logger }(app.logger)
+			app.logger = zap.New(core).Sugar()
+
+			req := newImpersonatedRequest(t, http.MethodPut, "/v1/users/3/follow")
+			expectActor()
+			mocks.Followers.EXPECT().Follow(gomock.Any(), int64(3), int64(2)).Return(false, errors.New("db is down"))
+			mocks.Audit.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusInternalServerError, t)
+			entries := logs.FilterMessage("internal server error").All()
+			if len(entries) != 1 || entries[0].ContextMap()["actor_id"] != int64(1) {
+				t.Errorf("expected internal server error logged with actor_id got %v", entries)
+			}
+		})
+
+	t.Run("Should_record_impersonated_write",
+		func(t *testing.T) {
+			req := newImpersonatedRequest(t, http.MethodPut, "/v1/users/3/follow")
+			expectActor()
+			mocks.Followers.EXPECT().Follow(gomock.Any(), int64(3), int64(2)).Return(false, nil)
+			mocks.Audit.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, e *store.AuditEntry) error {
+					if e.Action != store.AuditImpersonatedWrite || *e.ActorID != 1 || *e.TargetUserID != 2 {
+						t.Errorf("unexpected audit entry %+v", e)
+					}
+					return nil
+				})
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNoContent, t)
+		})
+
+	t.Run("Should_not_manage_account",
+		func(t *testing.T) {
+			req := newImpersonatedRequest(t, http.MethodGet, "/v1/users/me/sessions")
+			expectActor()
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+}
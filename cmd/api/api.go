@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -36,9 +37,48 @@ type config struct {
 	apiURL      string
 	mail        mailConfig
 	frontendURL string
+	// X-Forwarded-For and X-Real-IP are trusted only behind own proxy,
+	// otherwise a client could choose the IP used by login throttling
+	trustProxy  bool
 	auth        authConfig
 	redis       redisConfig
 	rateLimiter ratelimiter.Config
+	// Failed logins per account and IP
+	loginThrottle ratelimiter.LoginConfig
+	janitor       janitorConfig
+	export        exportConfig
+	scheduler     schedulerConfig
+}
+
+type janitorConfig struct {
+	interval time.Duration
+	// Not activated users are deleted after this period if their invitation expired
+	inactiveUserGrace time.Duration
+	// Deleted accounts are purged after this period
+	deletionGrace time.Duration
+	// deletionPolicyDelete or deletionPolicyAnonymize
+	deletionPolicy string
+	enabled        bool
+}
+
+type exportConfig struct {
+	// Archives are stored in this directory
+	dir string
+	// Token of the export is appended to this URL in the email
+	downloadURL string
+	// Download link and archive lifetime
+	exp time.Duration
+	// User can request one export per interval
+	minInterval time.Duration
+	// How often pending exports are checked
+	pollInterval time.Duration
+	enabled      bool
+}
+
+type schedulerConfig struct {
+	// How often scheduled posts are checked
+	interval time.Duration
+	enabled  bool
 }
 
 type redisConfig struct {
@@ -51,6 +91,8 @@ type redisConfig struct {
 type authConfig struct {
 	basic basicConfig
 	token tokenConfig
+	// External OpenID Connect providers, login is available by provider name
+	oidc []auth.OIDCConfig
 }
 
 type basicConfig struct {
@@ -59,9 +101,14 @@ type basicConfig struct {
 }
 
 type tokenConfig struct {
-	secret string
-	exp    time.Duration
-	iss    string
+	secret     string
+	exp        time.Duration
+	refreshExp time.Duration
+	iss        string
+	// RS256/EdDSA signing. HS256 with secret is used if signingKeyFile is empty
+	keyID            string
+	signingKeyFile   string
+	verificationKeys string // "kid1=path1,kid2=path2" public keys which are still trusted after rotation
 }
 
 type mailConfig struct {
@@ -69,6 +116,7 @@ type mailConfig struct {
 	mailTrap  mailTrapConfig
 	fromEmail string
 	exp       time.Duration
+	resetExp  time.Duration
 }
 
 type sendGridConfig struct {
@@ -87,13 +135,15 @@ type dbConfig struct {
 }
 
 type application struct {
-	config        config
-	store         store.Storage
-	cacheStorage  cache.Storage
-	logger        *zap.SugaredLogger
-	mailer        mailer.Client
-	authenticator auth.Authenticator
-	rateLimiter   ratelimiter.Limiter
+	config         config
+	store          store.Storage
+	cacheStorage   cache.Storage
+	logger         *zap.SugaredLogger
+	mailer         mailer.Client
+	authenticator  auth.Authenticator
+	rateLimiter    ratelimiter.Limiter
+	loginThrottler ratelimiter.LoginThrottler
+	oidcProviders  map[string]*auth.OIDCProvider
 }
 
 func (app *application) mount() http.Handler {
@@ -106,7 +156,7 @@ func (app *application) mount() http.Handler {
 		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
 		AllowedOrigins: []string{"https://*", "http://*"},
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
@@ -115,7 +165,9 @@ func (app *application) mount() http.Handler {
 
 	// A good base middleware stack
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	if app.config.trustProxy {
+		r.Use(middleware.RealIP)
+	}
 	//Adds information about each received request
 	r.Use(middleware.Logger)
 	//Recovers from panic and return internal server error
@@ -128,6 +180,9 @@ func (app *application) mount() http.Handler {
 	// processing should be stopped.
 	r.Use(middleware.Timeout(20 * time.Second))
 
+	// Public keys for services that verify our tokens. Not versioned because path is standard
+	r.Get("/.well-known/jwks.json", app.jwksHandler)
+
 	r.Route("/v1", func(r chi.Router) {
 		//Operations
 		//r.With(app.BasicAuthMiddleware()).Get("/health", app.healthCheckHandler)
@@ -138,45 +193,134 @@ func (app *application) mount() http.Handler {
 		r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsURL)))
 
 		// POST /v1/posts/
+		// Personal access tokens can call only routes with the scope of the token
 		r.Route("/posts", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
-			r.Post("/", app.createPostHandler)
+			r.With(app.RequireScope(store.ScopePostsWrite)).Post("/", app.createPostHandler)
+			r.With(app.RequireScope(store.ScopePostsRead)).Get("/drafts", app.getDraftsHandler)
 
 			r.Route("/{postID}", func(r chi.Router) {
 				// Routes that need the post loaded
 				r.With(app.postsContextMiddleware).Group(func(r chi.Router) {
-					r.Get("/", app.getPostHandler)
-					r.Patch("/", app.CheckPostOwnership(store.ModeratorRole, app.updatePostHandler))
-					r.Delete("/", app.CheckPostOwnership(store.AdminRole, app.deletePostHandler))
+					r.With(app.RequireScope(store.ScopePostsRead)).Get("/", app.getPostHandler)
+					r.With(app.RequireScope(store.ScopePostsWrite)).Patch("/", app.CheckPostOwnership(store.PermPostsUpdateAny, app.updatePostHandler))
+					r.With(app.RequireScope(store.ScopePostsWrite)).Delete("/", app.CheckPostOwnership(store.PermPostsDeleteAny, app.deletePostHandler))
+
+					// History is visible to those who can edit the post, of unpublished post only to the author
+					r.Route("/revisions", func(r chi.Router) {
+						r.With(app.RequireScope(store.ScopePostsRead)).Get("/", app.CheckPostOwnership(store.PermPostsUpdateAny, app.getRevisionsHandler))
+						r.With(app.RequireScope(store.ScopePostsRead)).Get("/diff", app.CheckPostOwnership(store.PermPostsUpdateAny, app.getRevisionDiffHandler))
+						r.With(app.RequireScope(store.ScopePostsRead)).Get("/{version}", app.CheckPostOwnership(store.PermPostsUpdateAny, app.getRevisionHandler))
+						r.With(app.RequireScope(store.ScopePostsWrite)).Post("/{version}/restore", app.CheckPostOwnership(store.PermPostsUpdateAny, app.restoreRevisionHandler))
+					})
 				})
 
 				// Comments for this post
 				r.Route("/comments", func(r chi.Router) {
-					r.Post("/", app.createCommentHandler)
+					r.With(app.postsContextMiddleware, app.RequireScope(store.ScopeCommentsWrite)).Post("/", app.createCommentHandler)
+					r.With(app.postsContextMiddleware, app.RequireScope(store.ScopeCommentsWrite)).Delete("/{commentID}", app.deleteCommentHandler)
 				})
 			})
 		})
 		r.Route("/users", func(r chi.Router) {
 			r.Put("/activate/{token}", app.activateUserHandler)
+			r.Put("/email/confirm/{token}", app.confirmEmailHandler)
+
+			r.Route("/me", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				// Account management is not available for personal access tokens
+				r.Use(app.SessionOnlyMiddleware)
+
+				r.Patch("/", app.updateProfileHandler)
+				r.Delete("/", app.deleteAccountHandler)
+				r.Patch("/password", app.changePasswordHandler)
+				r.Patch("/email", app.changeEmailHandler)
+
+				r.Route("/2fa", func(r chi.Router) {
+					r.Post("/", app.enrollTwoFactorHandler)
+					r.Post("/confirm", app.confirmTwoFactorHandler)
+					r.Delete("/", app.disableTwoFactorHandler)
+				})
+
+				r.Route("/tokens", func(r chi.Router) {
+					r.Get("/", app.getPersonalTokensHandler)
+					r.Post("/", app.createPersonalTokenHandler)
+					r.Delete("/{tokenID}", app.deletePersonalTokenHandler)
+				})
+
+				r.Route("/sessions", func(r chi.Router) {
+					r.Get("/", app.getSessionsHandler)
+					r.Delete("/", app.deleteOtherSessionsHandler)
+					r.Delete("/{sessionID}", app.deleteSessionHandler)
+				})
+
+				r.Post("/exports", app.requestDataExportHandler)
+
+				r.Route("/follow-requests", func(r chi.Router) {
+					r.Get("/", app.getFollowRequestsHandler)
+					r.Put("/{userID}/approve", app.approveFollowRequestHandler)
+					r.Put("/{userID}/reject", app.rejectFollowRequestHandler)
+				})
+			})
 
 			r.Route("/{userID}", func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
 
-				r.Get("/", app.getUserHandler)
-				r.Put("/follow", app.followUserHandler)
-				r.Put("/unfollow", app.unfollowUserHandler)
+				r.With(app.RequireScope(store.ScopeUsersRead)).Get("/", app.getUserHandler)
+				r.With(app.RequireScope(store.ScopeUsersRead)).Get("/followers", app.getFollowersHandler)
+				r.With(app.RequireScope(store.ScopeUsersRead)).Get("/following", app.getFollowingHandler)
+				r.With(app.RequireScope(store.ScopeUsersRead)).Get("/relationship", app.getRelationshipHandler)
+				r.With(app.RequireScope(store.ScopePostsRead)).Get("/posts", app.getUserPostsHandler)
+				r.With(app.RequireScope(store.ScopeUsersWrite)).Put("/follow", app.followUserHandler)
+				r.With(app.RequireScope(store.ScopeUsersWrite)).Put("/unfollow", app.unfollowUserHandler)
+				r.With(app.RequireScope(store.ScopeUsersWrite)).Put("/block", app.blockUserHandler)
+				r.With(app.RequireScope(store.ScopeUsersWrite)).Put("/unblock", app.unblockUserHandler)
+				r.With(app.RequireScope(store.ScopeUsersWrite)).Put("/mute", app.muteUserHandler)
+				r.With(app.RequireScope(store.ScopeUsersWrite)).Put("/unmute", app.unmuteUserHandler)
 			})
 
 			r.Group(func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
-				r.Get("/feed", app.getUserFeedHandler)
+				r.With(app.RequireScope(store.ScopeFeedRead)).Get("/feed", app.getUserFeedHandler)
+				r.With(app.RequireScope(store.ScopeUsersRead)).Get("/search", app.searchUsersHandler)
+				r.With(app.RequireScope(store.ScopeUsersRead)).Get("/suggestions", app.getSuggestionsHandler)
+			})
+		})
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.SessionOnlyMiddleware)
+
+			r.Route("/roles", func(r chi.Router) {
+				r.Use(app.RequirePermission(store.PermRolesManage))
+				r.Get("/", app.getRolesHandler)
+				r.Post("/", app.createRoleHandler)
+				r.Put("/{roleName}/permissions", app.setRolePermissionsHandler)
+			})
+			r.With(app.RequirePermission(store.PermAuditRead)).Get("/audit", app.getAuditLogHandler)
+
+			r.Route("/users/{userID}", func(r chi.Router) {
+				r.With(app.RequirePermission(store.PermRolesManage)).Put("/role", app.assignRoleHandler)
+				r.With(app.RequirePermission(store.PermUsersSuspend)).Put("/suspend", app.suspendUserHandler)
+				r.With(app.RequirePermission(store.PermUsersSuspend)).Put("/unsuspend", app.unsuspendUserHandler)
+				r.With(app.RequirePermission(store.PermUsersSuspend)).Get("/suspensions", app.getSuspensionsHandler)
+				r.With(app.RequirePermission(store.PermUsersImpersonate)).Post("/impersonate", app.impersonateUserHandler)
 			})
 		})
 		//Public routes
 		r.Route("/authentication", func(r chi.Router) {
 			r.Post("/user", app.registerUserHandler)
+			r.Post("/activation/resend", app.resendActivationHandler)
 			r.Post("/token", app.createTokenHandler)
+			r.Post("/token/2fa", app.createTwoFactorTokenHandler)
+			r.Post("/refresh", app.refreshTokenHandler)
+			r.Post("/logout", app.logoutHandler)
+			r.Get("/oidc/{provider}", app.oidcLoginHandler)
+			r.Get("/oidc/{provider}/callback", app.oidcCallbackHandler)
+			r.Post("/password/forgot", app.forgotPasswordHandler)
+			r.Post("/password/reset", app.resetPasswordHandler)
 		})
+		// Link from the email is opened in browser, so the token in URL is the only auth
+		r.Get("/exports/{token}", app.downloadDataExportHandler)
 
 	})
 
@@ -197,11 +341,26 @@ func (app *application) run(mux http.Handler) error {
 		IdleTimeout:  time.Minute,
 	}
 
+	//Background jobs are stopped together with the server
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	var jobs sync.WaitGroup
+	if app.config.janitor.enabled {
+		jobs.Go(func() { app.runJanitor(jobsCtx) })
+	}
+	if app.config.export.enabled {
+		jobs.Go(func() { app.runExporter(jobsCtx) })
+	}
+	if app.config.scheduler.enabled {
+		jobs.Go(func() { app.runScheduler(jobsCtx) })
+	}
+
 	shutdown := make(chan error)
 	go func() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		s := <-quit
+		stopJobs()
 
 		//Wait for some time to let active requests finist their work. New requests are not accepted
 		ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
@@ -221,6 +380,7 @@ func (app *application) run(mux http.Handler) error {
 	if err != nil {
 		return err
 	}
+	jobs.Wait()
 	app.logger.Infow("Server has stopped", "addr", app.config.addr, "env", app.config.env)
 	return nil
 }
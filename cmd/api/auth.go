@@ -14,17 +14,37 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	accessTokenType = "access"
+	// mfaTokenType proves that password was checked, it can't be used to access API
+	mfaTokenType = "mfa"
+	mfaTokenExp  = time.Minute * 5
+)
+
 type RegisterUserPayload struct {
 	Username string `json:"username" validate:"required,max=100"`
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Password string `json:"password" validate:"required,min=3,max=72"`
 }
 
+type ResendActivationPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
 type CreateUserTokenPayload struct {
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Password string `json:"password" validate:"required,min=3,max=72"`
 }
 
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=100"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
 type UserWithToken struct {
 	*store.User
 	Token string `json:"token"`
@@ -88,24 +108,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	//mail
-	activationURL := fmt.Sprintf(
-		"%s/confirm/%s",
-		app.config.frontendURL,
-		plainToken)
-	isProdEnv := app.config.env == "production"
-	vars := struct {
-		Username      string
-		ActivationURL string
-	}{
-		Username:      user.Username,
-		ActivationURL: activationURL,
-	}
-	code, err := app.mailer.Send(
-		mailer.UserWelcomeTemplate,
-		user.Username,
-		user.Email,
-		vars,
-		!isProdEnv)
+	code, err := app.sendActivationEmail(user, plainToken)
 	if err != nil {
 		app.logger.Errorw("error sending welcome email ", err.Error())
 		//rollback all changes in DB(SAGA pattern)
@@ -130,17 +133,92 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// ResendActivation godoc
+//
+//	@Summary		Resend activation email
+//	@Description	Sends a new activation link to a user who has not activated the account yet. Previous links stop working.
+//	@Description	Response is the same whether the email is registered or not
+//	@Tags			authentication
+//	@Accept			json
+//	@Param			payload	body	ResendActivationPayload	true	"User email"
+//	@Success		202		"Activation email sent if the user exists and is not activated"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Router			/authentication/activation/resend [post]
+func (app *application) resendActivationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResendActivationPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	plainToken := uuid.New().String()
+	user, err := app.store.Users.RenewInvitation(
+		r.Context(), payload.Email, plainToken, app.config.mail.exp)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			//Do not let the caller know which emails are registered
+			app.logger.Infow("activation resend requested for unknown or active email")
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	code, err := app.sendActivationEmail(user, plainToken)
+	if err != nil {
+		app.logger.Errorw("error sending activation email ", err.Error())
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.logger.Infow("Email sent", "status code", code)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (app *application) sendActivationEmail(user *store.User, plainToken string) (int, error) {
+	activationURL := fmt.Sprintf(
+		"%s/confirm/%s",
+		app.config.frontendURL,
+		plainToken)
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username      string
+		ActivationURL string
+	}{
+		Username:      user.Username,
+		ActivationURL: activationURL,
+	}
+
+	return app.mailer.Send(
+		mailer.UserWelcomeTemplate,
+		user.Username,
+		user.Email,
+		vars,
+		!isProdEnv)
+}
+
 // createTokenHandler godoc
 //
 //	@Summary		Creates a token
-//	@Description	Creates a token for a user whih then should be added into "Authorization" header for user identification
+//	@Description	Creates a short-lived access token whih then should be added into "Authorization" header for user identification
+//	@Description	and a refresh token that is used to get a new access token
 //	@Tags			authentication
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		CreateUserTokenPayload	true	"User credentials"
-//	@Success		201		{string}	string					"Token"
+//	@Success		201		{object}	main.envelopeSuccess{data=main.TokenPair}
+//	@Success		202		{object}	main.envelopeSuccess{data=main.TwoFactorChallenge}	"2FA is enabled, continue with /authentication/token/2fa"
 //	@Failure		400		{object}	main.envelopeErr
 //	@Failure		401		{object}	main.envelopeErr
+//	@Failure		423		{object}	main.envelopeErr	"Too many failed attempts, see Retry-After header"
 //	@Failure		500		{object}	main.envelopeErr
 //	@Router			/authentication/token [post]
 func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Request) {
@@ -155,11 +233,17 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	accountKey, ipKey := loginThrottleKeys(r, payload.Email)
+	if lockedFor := app.loginLockedFor(r.Context(), accountKey, ipKey); lockedFor > 0 {
+		app.accountLockedResponse(w, r, lockedFor)
+		return
+	}
+
 	user, err := app.store.Users.GetByEmail(r.Context(), payload.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
-			app.unauthorizedErrorResponse(w, r, err)
+			app.failedLoginResponse(w, r, payload.Email, nil, err)
 		default:
 			app.internalServerError(w, r, err)
 		}
@@ -167,26 +251,246 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	if err := user.Password.Compare(payload.Password); err != nil {
-		app.unauthorizedErrorResponse(w, r, err)
+		app.failedLoginResponse(w, r, payload.Email, user, err)
+		return
+	}
+
+	app.completeLogin(w, r, user)
+}
+
+// completeLogin is called after the user was authenticated by password or
+// external provider. Tokens are issued right away or after the second factor
+func (app *application) completeLogin(w http.ResponseWriter, r *http.Request, user *store.User) {
+	suspension, err := app.store.Suspensions.GetActive(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if suspension != nil {
+		app.accountSuspendedResponse(w, r, suspension)
+		return
+	}
+
+	//Password or identity provider proved the ownership, so the login cancels
+	//deletion before the second factor. Otherwise the 2FA step could not find the user
+	if user.DeletedAt != nil {
+		if err := app.store.Users.CancelDeletion(r.Context(), user.ID); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		app.logger.Infow("Account deletion cancelled by login", "user_id", user.ID)
+	}
+
+	totp, err := app.store.TwoFactor.Get(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if totp != nil && totp.Confirmed {
+		//Password is correct, but tokens are issued and failed attempts are reset only after the code is checked
+		mfaToken, err := app.generateMFAToken(user.ID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		challenge := TwoFactorChallenge{MFARequired: true, MFAToken: mfaToken}
+		if err := app.jsonResponse(w, http.StatusAccepted, challenge); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.loginSucceeded(r, user.Email)
+
+	//Every login starts a new session with its own family of refresh tokens
+	tokens, err := app.startSession(r, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// refreshTokenHandler godoc
+//
+//	@Summary		Refreshes a token
+//	@Description	Exchanges a refresh token for a new access and refresh tokens. Every refresh token can be used only once
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RefreshTokenPayload	true	"Refresh token"
+//	@Success		201		{object}	main.envelopeSuccess{data=main.TokenPair}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		401		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Router			/authentication/refresh [post]
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	refreshToken := uuid.New().String()
+	rt, err := app.store.RefreshTokens.Rotate(
+		r.Context(),
+		payload.RefreshToken,
+		refreshToken,
+		app.config.auth.token.refreshExp)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.unauthorizedErrorResponse(w, r, err)
+		case errors.Is(err, store.ErrTokenReused):
+			app.logger.Warnw("refresh token reuse, session revoked", "user_id", rt.UserID, "session_id", rt.FamilyID)
+			app.invalidateSessionsCache(r.Context(), rt.UserID)
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	session := sessionFromRequest(r, rt.UserID)
+	session.ID = rt.FamilyID
+	session.Expiry = rt.Expiry
+	if err := app.store.Sessions.Touch(r.Context(), session); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	accessToken, err := app.generateAccessToken(rt.UserID, rt.FamilyID)
+	if err != nil {
+		app.internalServerError(w, r, err)
 		return
 	}
 
+	tokens := TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// logoutHandler godoc
+//
+//	@Summary		Logout
+//	@Description	Revokes the refresh token and all tokens that were refreshed from the same login
+//	@Tags			authentication
+//	@Accept			json
+//	@Param			payload	body	RefreshTokenPayload	true	"Refresh token"
+//	@Success		204		"Logged out"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Router			/authentication/logout [post]
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userID, err := app.store.RefreshTokens.RevokeFamily(r.Context(), payload.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.invalidateSessionsCache(r.Context(), userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startSession creates a new session of the user, its first refresh token
+// and an access token bound to the session
+func (app *application) startSession(r *http.Request, userID int64) (TokenPair, error) {
+	session := sessionFromRequest(r, userID)
+	session.ID = uuid.New().String()
+	session.Expiry = time.Now().Add(app.config.auth.token.refreshExp)
+
+	refreshToken := uuid.New().String()
+	if err := app.store.Sessions.Create(r.Context(), session, refreshToken); err != nil {
+		return TokenPair{}, err
+	}
+	//Cached list of sessions does not have the new one
+	app.invalidateSessionsCache(r.Context(), userID)
+
+	accessToken, err := app.generateAccessToken(userID, session.ID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// generateAccessToken puts sessionID into "jti", so the token stops working
+// when the session is revoked
+func (app *application) generateAccessToken(userID int64, sessionID string) (string, error) {
 	//generate the token -> add claims
 	claims := jwt.MapClaims{
-		"sub": user.ID,
+		"sub": userID,
 		"exp": time.Now().Add(app.config.auth.token.exp).Unix(),
 		"iat": time.Now().Unix(),
 		"nbf": time.Now().Unix(),
 		"iss": app.config.auth.token.iss,
 		"aud": app.config.auth.token.iss,
+		"typ": accessTokenType,
+		"jti": sessionID,
 	}
-	token, err := app.authenticator.GenerateToken(claims)
-	if err != nil {
-		app.internalServerError(w, r, err)
-		return
+
+	return app.authenticator.GenerateToken(claims)
+}
+
+func (app *application) generateMFAToken(userID int64) (string, error) {
+	claims := jwt.MapClaims{
+		"sub": userID,
+		"exp": time.Now().Add(mfaTokenExp).Unix(),
+		"iat": time.Now().Unix(),
+		"nbf": time.Now().Unix(),
+		"iss": app.config.auth.token.iss,
+		"aud": app.config.auth.token.iss,
+		"typ": mfaTokenType,
 	}
 
-	if err := app.jsonResponse(w, http.StatusCreated, token); err != nil {
+	return app.authenticator.GenerateToken(claims)
+}
+
+// jwksHandler publishes public keys in JWKS format, so other services can verify
+// tokens without the signing secret. Response is not wrapped into the envelope
+// because clients expect the standard format
+func (app *application) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if err := writeJSON(w, http.StatusOK, app.authenticator.JWKS()); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
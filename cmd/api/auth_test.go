@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/auth"
+	"github.com/O-Nikitin/Social/internal/mailer"
+	"github.com/O-Nikitin/Social/internal/ratelimiter"
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+)
+
+func TestAuth_RefreshToken(t *testing.T) {
+	app, mocks := newTestApp(t, config{
+		auth: authConfig{
+			token: tokenConfig{
+				exp:        time.Minute,
+				refreshExp: time.Hour,
+			}}})
+	mux := app.mount()
+	body := `{"refresh_token": "old-token"}`
+
+	t.Run("Should_issue_new_token_pair",
+		func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				"/v1/authentication/refresh",
+				bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+
+			mocks.Refresh.EXPECT().
+				Rotate(gomock.Any(), "old-token", gomock.Any(), time.Hour).
+				Return(&store.RefreshToken{UserID: 7, FamilyID: "session-id"}, nil)
+			mocks.Sessions.EXPECT().
+				Touch(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, session *store.Session) error {
+					if session.ID != "session-id" || session.UserID != 7 {
+						t.Errorf("expected session %q of user 7 to be updated got %q of user %d",
+							"session-id", session.ID, session.UserID)
+					}
+					return nil
+				})
+			mocks.Auth.EXPECT().GenerateToken(gomock.Any()).Return("access-token", nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusCreated, t)
+
+			var response struct {
+				Data TokenPair `json:"data"`
+			}
+			json.Unmarshal(rr.Body.Bytes(), &response)
+
+			if response.Data.AccessToken != "access-token" {
+				t.Errorf("expected access token to be %q got %q", "access-token", response.Data.AccessToken)
+			}
+			if response.Data.RefreshToken == "" || response.Data.RefreshToken == "old-token" {
+				t.Errorf("expected refresh token to be rotated got %q", response.Data.RefreshToken)
+			}
+		})
+
+	t.Run("Should_not_allow_reused_token",
+		func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				"/v1/authentication/refresh",
+				bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+
+			mocks.Refresh.EXPECT().
+				Rotate(gomock.Any(), "old-token", gomock.Any(), time.Hour).
+				Return(&store.RefreshToken{UserID: 7, FamilyID: "session-id"}, store.ErrTokenReused)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusUnauthorized, t)
+		})
+}
+
+func TestAuth_MFATokenIsNotAccessToken(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+
+	req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+	if err != nil {
+		t.Fatal("Request not created: ", err)
+	}
+	req.Header.Set("Authorization", "Bearer mfa-token")
+
+	mocks.Auth.EXPECT().ValidateToken("mfa-token").Return(&jwt.Token{
+		Valid: true,
+		Claims: jwt.MapClaims{
+			"sub": float64(1),
+			"typ": mfaTokenType,
+		},
+	}, nil)
+
+	rr := executeRequest(req, mux)
+
+	checkResponseCode(rr.Code, http.StatusUnauthorized, t)
+}
+
+func TestAuth_RevokedSession(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+
+	req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+	if err != nil {
+		t.Fatal("Request not created: ", err)
+	}
+	req.Header.Set("Authorization", "Bearer access-token")
+
+	mocks.Auth.EXPECT().ValidateToken("access-token").Return(&jwt.Token{
+		Valid: true,
+		Claims: jwt.MapClaims{
+			"sub": float64(1),
+			"typ": accessTokenType,
+			"jti": "revoked-session",
+		},
+	}, nil)
+	mocks.Sessions.EXPECT().GetByUserID(gomock.Any(), int64(1)).Return([]store.Session{{ID: "active-session"}}, nil)
+
+	rr := executeRequest(req, mux)
+
+	checkResponseCode(rr.Code, http.StatusUnauthorized, t)
+}
+
+func TestAuth_PersonalTokenScopes(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	token := personalTokenPrefix + "abc123"
+	user := &store.User{ID: 1, Username: "bot"}
+
+	t.Run("Should_not_allow_route_without_scope",
+		func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				"/v1/posts",
+				bytes.NewBufferString(`{"title": "title", "content": "content"}`))
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			mocks.Tokens.EXPECT().Authenticate(gomock.Any(), token).Return(&store.PersonalToken{
+				UserID: user.ID,
+				Scopes: []string{store.ScopeFeedRead},
+			}, nil)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), user.ID).Return(user, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_not_allow_account_management",
+		func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/v1/users/me/tokens", nil)
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			mocks.Tokens.EXPECT().Authenticate(gomock.Any(), token).Return(&store.PersonalToken{
+				UserID: user.ID,
+				Scopes: []string{store.ScopeUsersRead, store.ScopeUsersWrite},
+			}, nil)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), user.ID).Return(user, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	newRequest := authRequests(app, mocks, user)
+
+	t.Run("Should_not_create_token_with_unknown_scope",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/users/me/tokens", `{"name": "bot", "scopes": ["posts:delete"]}`)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_create_token_with_scopes",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/users/me/tokens", `{"name": "bot", "scopes": ["posts:read", "feed:read"]}`)
+			mocks.Tokens.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusCreated, t)
+		})
+}
+
+func TestAuth_LoginThrottle(t *testing.T) {
+	app, mocks := newTestApp(t, config{
+		loginThrottle: ratelimiter.LoginConfig{
+			MaxAccountAttempts: 3,
+			MaxIPAttempts:      10,
+			Enabled:            true,
+		}})
+	mux := app.mount()
+	body := `{"email": "john@example.com", "password": "wrong-password"}`
+
+	t.Run("Should_not_check_password_while_locked",
+		func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				"/v1/authentication/token",
+				bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+
+			mocks.Login.EXPECT().Locked(gomock.Any(), "account:john@example.com").Return(90*time.Second, nil)
+			mocks.Login.EXPECT().Locked(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusLocked, t)
+			if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "90" {
+				t.Errorf("expected Retry-After to be %q got %q", "90", retryAfter)
+			}
+		})
+
+	t.Run("Should_lock_after_failed_attempt",
+		func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				"/v1/authentication/token",
+				bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+
+			user := &store.User{ID: 1, Email: "john@example.com"}
+			user.Password.Set("password")
+
+			mocks.Login.EXPECT().Locked(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil).Times(2)
+			mocks.Users.EXPECT().GetByEmail(gomock.Any(), "john@example.com").Return(user, nil)
+			mocks.Login.EXPECT().Fail(gomock.Any(), "account:john@example.com", 3).Return(time.Minute, nil)
+			mocks.Login.EXPECT().Fail(gomock.Any(), gomock.Any(), 10).Return(time.Duration(0), nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusLocked, t)
+		})
+}
+
+func TestAuth_LoginThrottleClientIP(t *testing.T) {
+	body := `{"email": "john@example.com", "password": "wrong-password"}`
+	throttle := ratelimiter.LoginConfig{MaxAccountAttempts: 3, MaxIPAttempts: 10, Enabled: true}
+
+	tests := []struct {
+		name       string
+		trustProxy bool
+		ipKey      string
+	}{
+		{"Should_ignore_forwarded_ip_from_client", false, "ip:203.0.113.5"},
+		{"Should_use_forwarded_ip_behind_trusted_proxy", true, "ip:198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mocks := newTestApp(t, config{loginThrottle: throttle, trustProxy: tt.trustProxy})
+			mux := app.mount()
+
+			req, err := http.NewRequest(http.MethodPost, "/v1/authentication/token", bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+			req.RemoteAddr = "203.0.113.5:4321"
+			req.Header.Set("X-Forwarded-For", "198.51.100.7")
+
+			mocks.Login.EXPECT().Locked(gomock.Any(), "account:john@example.com").Return(time.Duration(0), nil)
+			mocks.Login.EXPECT().Locked(gomock.Any(), tt.ipKey).Return(time.Duration(0), nil)
+			mocks.Users.EXPECT().GetByEmail(gomock.Any(), "john@example.com").Return(nil, store.ErrNotFound)
+			mocks.Login.EXPECT().Fail(gomock.Any(), "account:john@example.com", 3).Return(time.Duration(0), nil)
+			mocks.Login.EXPECT().Fail(gomock.Any(), tt.ipKey, 10).Return(time.Duration(0), nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusUnauthorized, t)
+		})
+	}
+}
+
+func TestAuth_RateLimiterClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		ip         string
+	}{
+		{"Should_limit_by_ip_without_port", false, "203.0.113.5"},
+		{"Should_limit_by_forwarded_ip_behind_trusted_proxy", true, "198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mocks := newTestApp(t, config{
+				rateLimiter: ratelimiter.Config{Enabled: true},
+				trustProxy:  tt.trustProxy,
+			})
+			mux := app.mount()
+
+			req, err := http.NewRequest(http.MethodGet, "/v1/health", nil)
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+			req.RemoteAddr = "203.0.113.5:4321"
+			req.Header.Set("X-Forwarded-For", "198.51.100.7")
+
+			mocks.Limiter.EXPECT().Allow(tt.ip).Return(false, 5*time.Second)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusTooManyRequests, t)
+		})
+	}
+}
+
+func TestAuth_ResendActivation(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	body := `{"email": "john@example.com"}`
+
+	t.Run("Should_not_reveal_unknown_email",
+		func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				"/v1/authentication/activation/resend",
+				bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+
+			mocks.Users.EXPECT().
+				RenewInvitation(gomock.Any(), "john@example.com", gomock.Any(), gomock.Any()).
+				Return(nil, store.ErrNotFound)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusAccepted, t)
+		})
+
+	t.Run("Should_send_new_activation_link",
+		func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				"/v1/authentication/activation/resend",
+				bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+
+			user := &store.User{ID: 1, Username: "john", Email: "john@example.com"}
+			mocks.Users.EXPECT().
+				RenewInvitation(gomock.Any(), "john@example.com", gomock.Any(), gomock.Any()).
+				Return(user, nil)
+			mocks.Mailer.EXPECT().
+				Send(mailer.UserWelcomeTemplate, "john", "john@example.com", gomock.Any(), gomock.Any()).
+				Return(http.StatusOK, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusAccepted, t)
+		})
+}
+
+func TestAuth_OIDCCallbackState(t *testing.T) {
+	app, _ := newTestApp(t, config{})
+	app.oidcProviders = map[string]*auth.OIDCProvider{
+		"mock": auth.NewOIDCProvider(auth.OIDCConfig{Name: "mock"}, nil),
+	}
+	mux := app.mount()
+
+	t.Run("Should_reject_unknown_provider",
+		func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/v1/authentication/oidc/unknown", nil)
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_reject_state_from_another_login",
+		func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodGet,
+				"/v1/authentication/oidc/mock/callback?code=code&state=forged",
+				nil)
+			if err != nil {
+				t.Fatal("Request not created: ", err)
+			}
+			req.AddCookie(&http.Cookie{Name: oidcCookieName, Value: "state.nonce.verifier"})
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+}
+
+func TestAuth_OIDCUsernameCollision(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	claims := &auth.IDTokenClaims{
+		RegisteredClaims:  jwt.RegisteredClaims{Subject: "provider-subject"},
+		Email:             "john@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "john",
+	}
+
+	t.Run("Should_retry_with_suffix_when_username_is_taken",
+		func(t *testing.T) {
+			var usernames []string
+			mocks.Ident.EXPECT().FindOrCreateUser(gomock.Any(), gomock.Any(), gomock.Any(), true).DoAndReturn(
+				func(_ context.Context, _ *store.Identity, u *store.User, _ bool) error {
+					usernames = append(usernames, u.Username)
+					if len(usernames) == 1 {
+						return store.ErrDuplicateUsername
+					}
+					u.ID = 1
+					return nil
+				}).Times(2)
+
+			user, err := app.oidcUser(context.Background(), "mock", claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.ID != 1 || usernames[0] != "john" || !strings.HasPrefix(usernames[1], "john_") {
+				t.Errorf("unexpected usernames %v", usernames)
+			}
+		})
+
+	t.Run("Should_give_up_after_attempts",
+		func(t *testing.T) {
+			mocks.Ident.EXPECT().FindOrCreateUser(gomock.Any(), gomock.Any(), gomock.Any(), true).
+				Return(store.ErrDuplicateUsername).Times(oidcUsernameAttempts)
+
+			if _, err := app.oidcUser(context.Background(), "mock", claims); !errors.Is(err, store.ErrDuplicateUsername) {
+				t.Errorf("expected %v got %v", store.ErrDuplicateUsername, err)
+			}
+		})
+
+	t.Run("Should_not_create_user_with_not_verified_email",
+		func(t *testing.T) {
+			unverified := *claims
+			unverified.EmailVerified = false
+			mocks.Ident.EXPECT().FindOrCreateUser(gomock.Any(), gomock.Any(), gomock.Any(), false).
+				Return(store.ErrEmailNotVerified)
+
+			if _, err := app.oidcUser(context.Background(), "mock", &unverified); !errors.Is(err, store.ErrEmailNotVerified) {
+				t.Errorf("expected %v got %v", store.ErrEmailNotVerified, err)
+			}
+		})
+}
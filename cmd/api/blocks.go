@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var errSelfTarget = errors.New("action is not allowed on yourself")
+
+// BlockUser godoc
+//
+//	@Summary		Blocks a user
+//	@Description	Blocked user can not follow the current user, comment on the posts and they do not see each other in the feed.
+//	@Description	Follows in both directions are removed
+//	@Tags			users
+//	@Param			userID	path	int	true	"userID"
+//	@Success		204		"User blocked"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr	"User not found"
+//	@Failure		409		{object}	main.envelopeErr	"User already blocked"
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/block [put]
+func (app *application) blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := getUserFromCtx(r)
+	userID, err := targetUserID(r, currentUser)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Blocks.Block(r.Context(), currentUser.ID, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	//Removed follows change counts of both users
+	app.invalidateUserCache(r.Context(), currentUser.ID)
+	app.invalidateUserCache(r.Context(), userID)
+	app.invalidateSuggestionsCache(r.Context(), currentUser.ID)
+	app.invalidateSuggestionsCache(r.Context(), userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnblockUser godoc
+//
+//	@Summary		Unblocks a user
+//	@Description	Unblocks a user by ID. Removed follows are not restored
+//	@Tags			users
+//	@Param			userID	path	int	true	"userID"
+//	@Success		204		"User unblocked"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/unblock [put]
+func (app *application) unblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := getUserFromCtx(r)
+	userID, err := targetUserID(r, currentUser)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Blocks.Unblock(r.Context(), currentUser.ID, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MuteUser godoc
+//
+//	@Summary		Mutes a user
+//	@Description	Posts of muted user are hidden from the feed. Muted user is not notified and can still follow and comment
+//	@Tags			users
+//	@Param			userID	path	int	true	"userID"
+//	@Success		204		"User muted"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr	"User not found"
+//	@Failure		409		{object}	main.envelopeErr	"User already muted"
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/mute [put]
+func (app *application) muteUserHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := getUserFromCtx(r)
+	userID, err := targetUserID(r, currentUser)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Blocks.Mute(r.Context(), currentUser.ID, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.invalidateSuggestionsCache(r.Context(), currentUser.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnmuteUser godoc
+//
+//	@Summary		Unmutes a user
+//	@Description	Unmutes a user by ID
+//	@Tags			users
+//	@Param			userID	path	int	true	"userID"
+//	@Success		204		"User unmuted"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/unmute [put]
+func (app *application) unmuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := getUserFromCtx(r)
+	userID, err := targetUserID(r, currentUser)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Blocks.Unmute(r.Context(), currentUser.ID, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// targetUserID returns userID from URL which must be another user
+func targetUserID(r *http.Request, currentUser *store.User) (int64, error) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if userID == currentUser.ID {
+		return 0, errSelfTarget
+	}
+
+	return userID, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -23,17 +24,12 @@ type CreateCommentPayload struct {
 //	@Param			body	body		main.CreateCommentPayload	true	"Comment data"
 //	@Success		201		{object}	main.envelopeSuccess{data=store.Comment}
 //	@Failure		400		{object}	main.envelopeErr	"User payload missing"
+//	@Failure		403		{object}	main.envelopeErr	"Author of the post blocked the user or was blocked"
+//	@Failure		404		{object}	main.envelopeErr
 //	@Failure		500		{object}	main.envelopeErr
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{postID}/comments [post]
 func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Request) {
-	paramID := chi.URLParam(r, "postID")
-	postID, err := strconv.ParseInt(paramID, 10, 64)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
-	}
-
 	var comment CreateCommentPayload
 	if err := readJSON(w, r, &comment); err != nil {
 		app.badRequestResponse(w, r, err)
@@ -45,8 +41,28 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+	if !app.canSeePost(w, r, post) {
+		return
+	}
+	if post.Status != store.PostPublished {
+		app.badRequestResponse(w, r, errPostNotPublished)
+		return
+	}
+
+	//Commenting is not allowed if the author of the post or the user blocked another
+	blocked, err := app.store.Blocks.IsBlocked(r.Context(), post.UserID, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if blocked {
+		app.forbiddenRepsonse(w, r)
+		return
+	}
+
 	DBcomment := &store.Comment{
-		PostID:  postID,
+		PostID:  post.ID,
 		UserID:  user.ID,
 		Content: *comment.Content,
 	}
@@ -61,3 +77,65 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 }
+
+// DeleteComment godoc
+//
+//	@Summary		Delete a comment
+//	@Description	Deletes own comment. Users with comments.delete.any permission can delete any comment
+//	@Tags			comments
+//	@Param			postID		path	int	true	"Post ID"
+//	@Param			commentID	path	int	true	"Comment ID"
+//	@Success		204			"Comment deleted"
+//	@Failure		400			{object}	main.envelopeErr
+//	@Failure		403			{object}	main.envelopeErr
+//	@Failure		404			{object}	main.envelopeErr
+//	@Failure		500			{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID} [delete]
+func (app *application) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	comment, err := app.store.Comments.GetByID(r.Context(), commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if comment.PostID != getPostFromCtx(r).ID {
+		app.notFoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	if comment.UserID != user.ID {
+		allowed, err := app.hasPermission(r.Context(), user, store.PermCommentsDeleteAny)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if !allowed {
+			app.forbiddenRepsonse(w, r)
+			return
+		}
+	}
+
+	if err := app.store.Comments.Delete(r.Context(), commentID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/golang/mock/gomock"
+)
+
+func TestComments_Delete(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	userRole := &store.Role{ID: 1, Name: store.UserRole, Level: 1}
+	moderatorRole := &store.Role{
+		ID:          2,
+		Name:        store.ModeratorRole,
+		Level:       2,
+		Permissions: []string{store.PermCommentsDeleteAny},
+	}
+	user := &store.User{ID: 1, Username: "john_doe", Role: *userRole}
+	moderator := &store.User{ID: 3, Username: "moderator", Role: *moderatorRole}
+	post := &store.Post{ID: 5, UserID: 2, Status: store.PostPublished}
+
+	asUser := authRequests(app, mocks, user)
+	asModerator := authRequests(app, mocks, moderator)
+
+	t.Run("Should_delete_own_comment",
+		func(t *testing.T) {
+			req := asUser(t, http.MethodDelete, "/v1/posts/5/comments/7", "")
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(5)).Return(post, nil)
+			mocks.Comments.EXPECT().GetByID(gomock.Any(), int64(7)).
+				Return(&store.Comment{ID: 7, PostID: 5, UserID: 1}, nil)
+			mocks.Comments.EXPECT().Delete(gomock.Any(), int64(7)).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNoContent, t)
+		})
+
+	t.Run("Should_not_delete_comment_of_other_user_without_permission",
+		func(t *testing.T) {
+			req := asUser(t, http.MethodDelete, "/v1/posts/5/comments/7", "")
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(5)).Return(post, nil)
+			mocks.Comments.EXPECT().GetByID(gomock.Any(), int64(7)).
+				Return(&store.Comment{ID: 7, PostID: 5, UserID: 2}, nil)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(1)).Return(userRole, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_delete_comment_of_other_user_with_permission",
+		func(t *testing.T) {
+			req := asModerator(t, http.MethodDelete, "/v1/posts/5/comments/7", "")
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(5)).Return(post, nil)
+			mocks.Comments.EXPECT().GetByID(gomock.Any(), int64(7)).
+				Return(&store.Comment{ID: 7, PostID: 5, UserID: 2}, nil)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(2)).Return(moderatorRole, nil)
+			mocks.Comments.EXPECT().Delete(gomock.Any(), int64(7)).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNoContent, t)
+		})
+}
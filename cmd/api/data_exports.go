@@ -0,0 +1,263 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/mailer"
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// RequestDataExport godoc
+//
+//	@Summary		Request data export
+//	@Description	Starts building a ZIP archive with profile, posts, comments, follows and sessions of the current user.
+//	@Description	Download link is sent by email when the archive is ready. One export can be requested per day
+//	@Tags			users
+//	@Produce		json
+//	@Success		202	{object}	main.envelopeSuccess{data=store.DataExport}
+//	@Failure		401	{object}	main.envelopeErr
+//	@Failure		429	{object}	main.envelopeErr
+//	@Failure		500	{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/exports [post]
+func (app *application) requestDataExportHandler(w http.ResponseWriter, r *http.Request) {
+	export := &store.DataExport{UserID: getUserFromCtx(r).ID}
+
+	err := app.store.DataExports.Create(r.Context(), export, app.config.export.minInterval)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrExportLimited):
+			retryAfter := math.Ceil(app.config.export.minInterval.Seconds())
+			app.rateLimitExceededResponse(w, r, strconv.Itoa(int(retryAfter)))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, export); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DownloadDataExport godoc
+//
+//	@Summary		Download data export
+//	@Description	Downloads the archive by the token from the email
+//	@Tags			users
+//	@Produce		application/zip
+//	@Param			token	path	string	true	"Export token"
+//	@Success		200		"ZIP archive"
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Router			/exports/{token} [get]
+func (app *application) downloadDataExportHandler(w http.ResponseWriter, r *http.Request) {
+	export, err := app.store.DataExports.GetByToken(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	file, err := os.Open(app.exportPath(export.ID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			app.notFoundResponse(w, r, store.ErrNotFound)
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="social-export-%d.zip"`, export.ID))
+	http.ServeContent(w, r, "", stat.ModTime(), file)
+}
+
+// runExporter builds requested archives. Exports are claimed in DB,
+// so several instances of the API can run it. It returns when ctx is cancelled
+func (app *application) runExporter(ctx context.Context) {
+	if err := os.MkdirAll(app.config.export.dir, 0o700); err != nil {
+		app.logger.Errorw("error creating export directory", "dir", app.config.export.dir, "err", err.Error())
+		return
+	}
+
+	ticker := time.NewTicker(app.config.export.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		app.processExports(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) processExports(ctx context.Context) {
+	for ctx.Err() == nil {
+		export, err := app.store.DataExports.ClaimPending(ctx)
+		if err != nil {
+			if !errors.Is(err, store.ErrNotFound) {
+				app.logger.Errorw("error claiming data export", "err", err.Error())
+			}
+			return
+		}
+
+		app.processExport(ctx, export)
+	}
+}
+
+func (app *application) processExport(ctx context.Context, export *store.DataExport) {
+	data, err := app.store.DataExports.Collect(ctx, export.UserID)
+	if err == nil {
+		err = app.writeExportFile(export.ID, data)
+	}
+
+	token := uuid.New().String()
+	export.Status = store.ExportReady
+	if err != nil {
+		app.logger.Errorw("error building data export", "export_id", export.ID, "err", err.Error())
+		export.Status = store.ExportFailed
+	}
+
+	if err := app.store.DataExports.Finish(ctx, export, token, app.config.export.exp); err != nil {
+		app.logger.Errorw("error finishing data export", "export_id", export.ID, "err", err.Error())
+		return
+	}
+	if export.Status != store.ExportReady {
+		return
+	}
+
+	vars := struct {
+		Username    string
+		DownloadURL string
+		ExpiresAt   string
+	}{
+		Username:    data.Profile.Username,
+		DownloadURL: fmt.Sprintf("%s/%s", app.config.export.downloadURL, token),
+		ExpiresAt:   *export.Expiry,
+	}
+	isProdEnv := app.config.env == "production"
+	code, err := app.mailer.Send(
+		mailer.DataExportTemplate,
+		data.Profile.Username,
+		data.Profile.Email,
+		vars,
+		!isProdEnv)
+	if err != nil {
+		app.logger.Errorw("error sending data export email", "export_id", export.ID, "err", err.Error())
+		return
+	}
+	app.logger.Infow("Data export sent", "export_id", export.ID, "status code", code)
+}
+
+// writeExportFile writes the archive to a temporary file first,
+// so a partly written archive is never downloaded
+func (app *application) writeExportFile(exportID int64, data *store.UserData) error {
+	tmp, err := os.CreateTemp(app.config.export.dir, "export-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeExportArchive(tmp, data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), app.exportPath(exportID))
+}
+
+// writeExportArchive writes ZIP archive with one JSON file per kind of data
+func writeExportArchive(w io.Writer, data *store.UserData) error {
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", data.Profile},
+		{"posts.json", data.Posts},
+		{"comments.json", data.Comments},
+		{"followers.json", data.Followers},
+		{"following.json", data.Following},
+		{"sessions.json", data.Sessions},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		fw, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// removeExpiredExports removes archives which can not be downloaded anymore
+func (app *application) removeExpiredExports() (int, error) {
+	entries, err := os.ReadDir(app.config.export.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		if time.Since(info.ModTime()) < app.config.export.exp {
+			continue
+		}
+		if err := os.Remove(filepath.Join(app.config.export.dir, entry.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+func (app *application) exportPath(exportID int64) string {
+	return filepath.Join(app.config.export.dir, strconv.FormatInt(exportID, 10)+".zip")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/mailer"
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/golang/mock/gomock"
+)
+
+func TestDataExports(t *testing.T) {
+	app, mocks := newTestApp(t, config{export: exportConfig{
+		dir:         t.TempDir(),
+		downloadURL: "http://localhost:3000/v1/exports",
+		exp:         time.Hour,
+		minInterval: 24 * time.Hour,
+	}})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe", Email: "john@example.com"}
+	newRequest := authRequests(app, mocks, user)
+
+	t.Run("Should_allow_one_export_per_interval",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/users/me/exports", "")
+			mocks.Exports.EXPECT().Create(gomock.Any(), gomock.Any(), 24*time.Hour).Return(store.ErrExportLimited)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusTooManyRequests, t)
+			if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "86400" {
+				t.Errorf("expected Retry-After to be %q got %q", "86400", retryAfter)
+			}
+		})
+
+	t.Run("Should_build_archive_and_send_link",
+		func(t *testing.T) {
+			export := &store.DataExport{ID: 7, UserID: 1, Status: store.ExportProcessing}
+			expiry := "2026-01-01 00:00:00+00"
+			mocks.Exports.EXPECT().Collect(gomock.Any(), int64(1)).Return(&store.UserData{
+				Profile: *user,
+				Posts:   []store.ExportedPost{{ID: 1, Title: "Hello", Tags: []string{"go"}}},
+			}, nil)
+			mocks.Exports.EXPECT().Finish(gomock.Any(), export, gomock.Any(), time.Hour).
+				DoAndReturn(func(_ context.Context, e *store.DataExport, _ string, _ time.Duration) error {
+					if e.Status != store.ExportReady {
+						t.Errorf("expected status %q got %q", store.ExportReady, e.Status)
+					}
+					e.Expiry = &expiry
+					return nil
+				})
+			mocks.Mailer.EXPECT().
+				Send(mailer.DataExportTemplate, "john_doe", "john@example.com", gomock.Any(), true).
+				Return(http.StatusOK, nil)
+
+			app.processExport(t.Context(), export)
+
+			zr, err := zip.OpenReader(app.exportPath(export.ID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer zr.Close()
+
+			names := map[string]bool{}
+			for _, f := range zr.File {
+				names[f.Name] = true
+			}
+			for _, want := range []string{"profile.json", "posts.json", "comments.json",
+				"followers.json", "following.json", "sessions.json"} {
+				if !names[want] {
+					t.Errorf("expected %s in archive got %v", want, names)
+				}
+			}
+		})
+
+	t.Run("Should_remove_expired_archives",
+		func(t *testing.T) {
+			old := time.Now().Add(-2 * time.Hour)
+			if err := os.Chtimes(app.exportPath(7), old, old); err != nil {
+				t.Fatal(err)
+			}
+
+			removed, err := app.removeExpiredExports()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed != 1 {
+				t.Errorf("expected 1 archive removed got %d", removed)
+			}
+		})
+}
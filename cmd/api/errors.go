@@ -1,13 +1,32 @@
 package main
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"go.uber.org/zap"
 )
 
+type loggerKey string
+
+// loggerCtx has the logger with fields of the request, e.g. the admin who impersonates the user
+const loggerCtx loggerKey = "logger"
+
+// requestLogger returns the logger of the request or the app logger
+func (app *application) requestLogger(r *http.Request) *zap.SugaredLogger {
+	if logger, ok := r.Context().Value(loggerCtx).(*zap.SugaredLogger); ok {
+		return logger
+	}
+	return app.logger
+}
+
 func (app *application) internalServerError(
 	w http.ResponseWriter, r *http.Request, err error) {
 
-	app.logger.Errorw(
+	app.requestLogger(r).Errorw(
 		"internal server error",
 		"method", r.Method,
 		"path", r.URL.Path,
@@ -21,7 +40,7 @@ func (app *application) internalServerError(
 func (app *application) badRequestResponse(
 	w http.ResponseWriter, r *http.Request, err error) {
 
-	app.logger.Warnw(
+	app.requestLogger(r).Warnw(
 		"bad request error",
 		"method", r.Method,
 		"path", r.URL.Path,
@@ -36,7 +55,7 @@ func (app *application) badRequestResponse(
 func (app *application) conflictResponse(
 	w http.ResponseWriter, r *http.Request, err error) {
 
-	app.logger.Errorw(
+	app.requestLogger(r).Errorw(
 		"conflict error",
 		"method", r.Method,
 		"path", r.URL.Path,
@@ -51,7 +70,7 @@ func (app *application) conflictResponse(
 func (app *application) notFoundResponse(
 	w http.ResponseWriter, r *http.Request, err error) {
 
-	app.logger.Warnw(
+	app.requestLogger(r).Warnw(
 		"not found error",
 		"method", r.Method,
 		"path", r.URL.Path,
@@ -64,13 +83,13 @@ func (app *application) notFoundResponse(
 }
 
 func (app *application) unauthorizedErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Warnw("unauthorized error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+	app.requestLogger(r).Warnw("unauthorized error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
 
 	writeJSONError(w, http.StatusUnauthorized, "unauthorized")
 }
 
 func (app *application) unauthorizedBasicErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Warnw("unauthorized basic error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+	app.requestLogger(r).Warnw("unauthorized basic error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
 
 	w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
 
@@ -78,15 +97,34 @@ func (app *application) unauthorizedBasicErrorResponse(w http.ResponseWriter, r
 }
 
 func (app *application) forbiddenRepsonse(w http.ResponseWriter, r *http.Request) {
-	app.logger.Warnw("forbidden error", "method", r.Method, "path", r.URL.Path)
+	app.requestLogger(r).Warnw("forbidden error", "method", r.Method, "path", r.URL.Path)
 
 	writeJSONError(w, http.StatusForbidden, "forbidden")
 }
 
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter string) {
-	app.logger.Warnw("rate limit exceeded", "method", r.Method, "path", r.URL.Path)
+	app.requestLogger(r).Warnw("rate limit exceeded", "method", r.Method, "path", r.URL.Path)
 
 	w.Header().Set("Retry-After", retryAfter)
 
 	writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded, retry after: "+retryAfter)
 }
+
+func (app *application) accountLockedResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	app.requestLogger(r).Warnw("login locked", "method", r.Method, "path", r.URL.Path, "retry_after", retryAfter)
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	writeJSONError(w, http.StatusLocked,
+		"too many failed login attempts, retry after: "+retryAfter.Round(time.Second).String())
+}
+
+func (app *application) accountSuspendedResponse(w http.ResponseWriter, r *http.Request, suspension *store.Suspension) {
+	app.requestLogger(r).Warnw("suspended user", "method", r.Method, "path", r.URL.Path, "user_id", suspension.UserID)
+
+	until := "permanently"
+	if suspension.ExpiresAt != nil {
+		until = "until " + *suspension.ExpiresAt
+	}
+	writeJSONError(w, http.StatusForbidden, "account is suspended "+until+": "+suspension.Reason)
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/go-chi/chi/v5"
 )
 
 // getUserFeedHandler godoc
@@ -58,3 +61,81 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 }
+
+// getUserPostsHandler godoc
+//
+//	@Summary		Fetches posts of the user
+//	@Description	Posts of one user for the profile page. Posts of private user are visible only to approved followers,
+//	@Description	posts of suspended user and of the user who blocked the current user or was blocked are not found
+//	@Tags			feed
+//	@Produce		json
+//	@Param			userID	path		int		true	"userID"
+//	@Param			since	query		string	false	"Since"
+//	@Param			until	query		string	false	"Until"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			sort	query		string	false	"Sort"
+//	@Param			tags	query		string	false	"Tags"
+//	@Param			search	query		string	false	"Search"
+//	@Success		200		{object}	main.envelopeSuccess{data=[]store.PostWithMetadata}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/posts [get]
+func (app *application) getUserPostsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	fq := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+	fq, err = fq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if _, err := app.getUser(r.Context(), userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if !app.canSeePosts(w, r, userID) {
+		return
+	}
+
+	blocked, err := app.store.Blocks.IsBlocked(r.Context(), userID, getUserFromCtx(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if blocked {
+		app.notFoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+
+	posts, err := app.store.Posts.GetByUserID(r.Context(), userID, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
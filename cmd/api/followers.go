@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetFollowers godoc
+//
+//	@Summary		Get followers
+//	@Description	Get users who follow the user, newest first
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int	true	"userID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	main.envelopeSuccess{data=[]store.FollowEntry}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/followers [get]
+func (app *application) getFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	app.followListResponse(w, r, app.store.Followers.GetFollowers)
+}
+
+// GetFollowing godoc
+//
+//	@Summary		Get followed users
+//	@Description	Get users who are followed by the user, newest first
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int	true	"userID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	main.envelopeSuccess{data=[]store.FollowEntry}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/following [get]
+func (app *application) getFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	app.followListResponse(w, r, app.store.Followers.GetFollowing)
+}
+
+// GetRelationship godoc
+//
+//	@Summary		Get relationship
+//	@Description	Shows if the current user and the user follow each other
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int	true	"userID"
+//	@Success		200		{object}	main.envelopeSuccess{data=store.Relationship}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/relationship [get]
+func (app *application) getRelationshipHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rel, err := app.store.Followers.GetRelationship(r.Context(), getUserFromCtx(r).ID, userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, rel); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type followListFunc func(ctx context.Context, userID int64, q store.PaginatedQuery) ([]store.FollowEntry, error)
+
+func (app *application) followListResponse(w http.ResponseWriter, r *http.Request, list followListFunc) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	q := store.PaginatedQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+	q, err = q.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	//Empty list of not existing user would look like user without followers
+	if _, err := app.getUser(r.Context(), userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	entries, err := list(r.Context(), userID, q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, entries); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetFollowRequests godoc
+//
+//	@Summary		Get follow requests
+//	@Description	Get pending follow requests to the current user, oldest first
+//	@Tags			users
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	main.envelopeSuccess{data=[]store.FollowRequest}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		401		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests [get]
+func (app *application) getFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	q := store.PaginatedQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	requests, err := app.store.Followers.GetRequests(r.Context(), getUserFromCtx(r).ID, q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, requests); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ApproveFollowRequest godoc
+//
+//	@Summary		Approve follow request
+//	@Description	The user who sent the request becomes a follower of the current user
+//	@Tags			users
+//	@Param			userID	path	int	true	"ID of the user who sent the request"
+//	@Success		204		"Follow request approved"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr	"One of the users blocked another"
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{userID}/approve [put]
+func (app *application) approveFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := getUserFromCtx(r)
+	requesterID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Followers.ApproveRequest(r.Context(), currentUser.ID, requesterID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrBlocked):
+			app.forbiddenRepsonse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.invalidateUserCache(r.Context(), currentUser.ID)
+	app.invalidateUserCache(r.Context(), requesterID)
+	app.invalidateSuggestionsCache(r.Context(), requesterID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RejectFollowRequest godoc
+//
+//	@Summary		Reject follow request
+//	@Description	Removes follow request, the user who sent it is not notified
+//	@Tags			users
+//	@Param			userID	path	int	true	"ID of the user who sent the request"
+//	@Success		204		"Follow request rejected"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{userID}/reject [put]
+func (app *application) rejectFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	requesterID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Followers.RejectRequest(r.Context(), getUserFromCtx(r).ID, requesterID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
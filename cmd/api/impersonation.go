@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Impersonation token is bound to the session of the admin, so logout of
+// the admin or revoke of the session ends impersonation before expiry
+const impersonationTokenExp = time.Minute * 15
+
+var errImpersonationNotAllowed = errors.New("impersonation is not allowed")
+
+type actorKey string
+
+// actorCtx is the admin who acts as the user from userCtx
+const actorCtx actorKey = "actor"
+
+type ImpersonationToken struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// ImpersonateUser godoc
+//
+//	@Summary		Impersonate a user
+//	@Description	Issues short-lived access token to act as the user, e.g. to debug the feed. The token has the user
+//	@Description	in "sub" and the admin with the session in "act" claim. Impersonation ends when the admin logs out or
+//	@Description	revokes the session. Account management is not available with the token,
+//	@Description	every write request is recorded in the audit log
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userID	path		int	true	"userID"
+//	@Success		201		{object}	main.envelopeSuccess{data=main.ImpersonationToken}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/impersonate [post]
+func (app *application) impersonateUserHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := getUserFromCtx(r)
+	userID, err := targetUserID(r, currentUser)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.store.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if user.Role.Level >= currentUser.Role.Level {
+		app.forbiddenRepsonse(w, r)
+		return
+	}
+	//Token issued before sessions were added can't end impersonation
+	sessionID := getSessionIDFromCtx(r)
+	if sessionID == "" {
+		app.forbiddenRepsonse(w, r)
+		return
+	}
+
+	expiresAt := time.Now().Add(impersonationTokenExp)
+	claims := jwt.MapClaims{
+		"sub": user.ID,
+		"act": map[string]any{"sub": currentUser.ID, "sid": sessionID},
+		"exp": expiresAt.Unix(),
+		"iat": time.Now().Unix(),
+		"nbf": time.Now().Unix(),
+		"iss": app.config.auth.token.iss,
+		"aud": app.config.auth.token.iss,
+		"typ": accessTokenType,
+	}
+	token, err := app.authenticator.GenerateToken(claims)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	details, err := json.Marshal(map[string]string{"expires_at": expiresAt.UTC().Format(time.RFC3339)})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	entry := &store.AuditEntry{
+		ActorID:      &currentUser.ID,
+		Action:       store.AuditImpersonationStarted,
+		TargetUserID: &user.ID,
+		Details:      details,
+	}
+	if err := app.store.Audit.Create(r.Context(), entry); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := ImpersonationToken{Token: token, ExpiresAt: expiresAt.UTC().Format(time.RFC3339)}
+	if err := app.jsonResponse(w, http.StatusCreated, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// impersonationActor returns the admin from "act" claim. The session and the
+// permission are checked again, so the token stops working when the admin
+// logs out or loses the permission
+func (app *application) impersonationActor(ctx context.Context, act map[string]any) (*store.User, error) {
+	actorID, err := userIDFromClaims(act)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, _ := act["sid"].(string)
+	if sessionID == "" {
+		return nil, errImpersonationNotAllowed
+	}
+	if err := app.checkSession(ctx, actorID, sessionID); err != nil {
+		return nil, err
+	}
+
+	actor, err := app.getUser(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if actor.Suspension.Active() {
+		return nil, errImpersonationNotAllowed
+	}
+
+	allowed, err := app.hasPermission(ctx, actor, store.PermUsersImpersonate)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, errImpersonationNotAllowed
+	}
+	return actor, nil
+}
+
+// serveImpersonated logs every request made with impersonation token and
+// records writes in the audit log after they are done
+func (app *application) serveImpersonated(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	actor := getActorFromCtx(r)
+	user := getUserFromCtx(r)
+
+	//Errors of the handlers are logged with the admin
+	logger := app.logger.With("actor_id", actor.ID, "user_id", user.ID)
+	r = r.WithContext(context.WithValue(r.Context(), loggerCtx, logger))
+
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	next.ServeHTTP(ww, r)
+
+	logger.Infow("impersonated request", "method", r.Method, "path", r.URL.Path, "status", ww.Status())
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return
+	}
+
+	details, err := json.Marshal(map[string]any{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"status": ww.Status(),
+	})
+	if err != nil {
+		logger.Errorw("error recording impersonated write", "err", err.Error())
+		return
+	}
+	entry := &store.AuditEntry{
+		ActorID:      &actor.ID,
+		Action:       store.AuditImpersonatedWrite,
+		TargetUserID: &user.ID,
+		Details:      details,
+	}
+	//The write is done, so it is recorded even if the client has gone
+	if err := app.store.Audit.Create(context.WithoutCancel(r.Context()), entry); err != nil {
+		logger.Errorw("error recording impersonated write", "err", err.Error())
+	}
+}
+
+// getActorFromCtx returns the admin if the request is made with impersonation token
+func getActorFromCtx(r *http.Request) *store.User {
+	actor, _ := r.Context().Value(actorCtx).(*store.User)
+	return actor
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Policies of purging deleted accounts
+const (
+	// User is removed with posts and comments
+	deletionPolicyDelete = "delete"
+	// Personal data of the user is removed, posts and comments are kept
+	deletionPolicyAnonymize = "anonymize"
+)
+
+// runJanitor periodically removes data which is not needed anymore.
+// It returns when ctx is cancelled
+func (app *application) runJanitor(ctx context.Context) {
+	ticker := time.NewTicker(app.config.janitor.interval)
+	defer ticker.Stop()
+
+	for {
+		app.cleanup(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) cleanup(ctx context.Context) {
+	users, err := app.store.Users.DeleteNotActivated(ctx, app.config.janitor.inactiveUserGrace)
+	if err != nil {
+		app.logger.Errorw("error deleting not activated users", "err", err.Error())
+	} else if users > 0 {
+		app.logger.Infow("Not activated users deleted", "count", users)
+	}
+
+	invitations, err := app.store.Users.DeleteExpiredInvitations(ctx)
+	if err != nil {
+		app.logger.Errorw("error deleting expired invitations", "err", err.Error())
+	} else if invitations > 0 {
+		app.logger.Infow("Expired invitations deleted", "count", invitations)
+	}
+
+	anonymize := app.config.janitor.deletionPolicy == deletionPolicyAnonymize
+	purged, err := app.store.Users.PurgeDeleted(ctx, app.config.janitor.deletionGrace, anonymize)
+	if err != nil {
+		app.logger.Errorw("error purging deleted accounts", "err", err.Error())
+	} else if purged > 0 {
+		app.logger.Infow("Deleted accounts purged", "count", purged, "policy", app.config.janitor.deletionPolicy)
+	}
+
+	//Archives are removed by age, so archives of purged accounts are removed as well
+	exports, err := app.removeExpiredExports()
+	if err != nil {
+		app.logger.Errorw("error removing expired data exports", "err", err.Error())
+	} else if exports > 0 {
+		app.logger.Infow("Expired data exports removed", "count", exports)
+	}
+}
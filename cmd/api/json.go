@@ -3,7 +3,9 @@ package main
 import (
 	"encoding/json"
 	"net/http"
+	"slices"
 
+	"github.com/O-Nikitin/Social/internal/store"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -19,6 +21,19 @@ type envelopeSuccess struct {
 
 func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
+	//Names of permissions and scopes are kept in one place, so there is no "oneof" list
+	registerOneOf("permission", store.Permissions)
+	registerOneOf("scope", store.Scopes)
+}
+
+// registerOneOf adds validation tag which accepts only the values
+func registerOneOf(tag string, values []string) {
+	err := Validate.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+		return slices.Contains(values, fl.Field().String())
+	})
+	if err != nil {
+		panic(err)
+	}
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) error {
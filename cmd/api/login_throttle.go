@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/mailer"
+	"github.com/O-Nikitin/Social/internal/store"
+)
+
+// loginThrottleKeys returns keys for failed attempts of the account and of the client IP.
+// Account key uses email, so attempts for not registered emails are limited as well
+func loginThrottleKeys(r *http.Request, email string) (string, string) {
+	return "account:" + strings.ToLower(email), "ip:" + clientIP(r)
+}
+
+// clientIP returns IP without port, port is different for every connection.
+// RemoteAddr is replaced with the address from proxy headers only when TRUST_PROXY is set
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+// loginLockedFor returns the longest lock of the keys.
+// Throttler errors are only logged so the login does not depend on redis
+func (app *application) loginLockedFor(ctx context.Context, keys ...string) time.Duration {
+	if !app.config.loginThrottle.Enabled {
+		return 0
+	}
+
+	var lockedFor time.Duration
+	for _, key := range keys {
+		locked, err := app.loginThrottler.Locked(ctx, key)
+		if err != nil {
+			app.logger.Errorw("error checking login lock", "key", key, "err", err.Error())
+			continue
+		}
+		lockedFor = max(lockedFor, locked)
+	}
+
+	return lockedFor
+}
+
+// loginFailed registers failed attempt for the account and the client IP and
+// returns for how long login is locked now. user is nil if email is not registered
+func (app *application) loginFailed(r *http.Request, email string, user *store.User) time.Duration {
+	if !app.config.loginThrottle.Enabled {
+		return 0
+	}
+
+	cfg := app.config.loginThrottle
+	accountKey, ipKey := loginThrottleKeys(r, email)
+
+	accountLock, err := app.loginThrottler.Fail(r.Context(), accountKey, cfg.MaxAccountAttempts)
+	if err != nil {
+		app.logger.Errorw("error registering failed login", "key", accountKey, "err", err.Error())
+	}
+	ipLock, err := app.loginThrottler.Fail(r.Context(), ipKey, cfg.MaxIPAttempts)
+	if err != nil {
+		app.logger.Errorw("error registering failed login", "key", ipKey, "err", err.Error())
+	}
+
+	if accountLock > 0 {
+		app.logger.Warnw("account locked after failed logins", "email", email, "ip", ipKey, "locked_for", accountLock)
+		if user != nil && cfg.NotifyUser {
+			app.sendLoginAlert(user, strings.TrimPrefix(ipKey, "ip:"), accountLock)
+		}
+	}
+
+	return max(accountLock, ipLock)
+}
+
+// failedLoginResponse answers with 423 if this attempt locked the login
+func (app *application) failedLoginResponse(
+	w http.ResponseWriter, r *http.Request, email string, user *store.User, err error) {
+
+	if lockedFor := app.loginFailed(r, email, user); lockedFor > 0 {
+		app.accountLockedResponse(w, r, lockedFor)
+		return
+	}
+
+	app.unauthorizedErrorResponse(w, r, err)
+}
+
+// loginSucceeded forgets failed attempts of the account.
+// IP attempts are not reset, otherwise attacker could reset them with own account
+func (app *application) loginSucceeded(r *http.Request, email string) {
+	if !app.config.loginThrottle.Enabled {
+		return
+	}
+
+	accountKey, _ := loginThrottleKeys(r, email)
+	if err := app.loginThrottler.Reset(r.Context(), accountKey); err != nil {
+		app.logger.Errorw("error resetting failed logins", "key", accountKey, "err", err.Error())
+	}
+}
+
+// sendLoginAlert does not block the login response because mailer retries with delays
+func (app *application) sendLoginAlert(user *store.User, ip string, lockedFor time.Duration) {
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username  string
+		IP        string
+		LockedFor string
+	}{
+		Username:  user.Username,
+		IP:        ip,
+		LockedFor: lockedFor.Round(time.Second).String(),
+	}
+
+	go func() {
+		code, err := app.mailer.Send(
+			mailer.LoginAlertTemplate,
+			user.Username,
+			user.Email,
+			vars,
+			!isProdEnv)
+		if err != nil {
+			app.logger.Errorw("error sending login alert email", "user_id", user.ID, "err", err.Error())
+			return
+		}
+		app.logger.Infow("Email sent", "status code", code)
+	}()
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"expvar"
+	"fmt"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/O-Nikitin/Social/internal/auth"
@@ -52,20 +54,27 @@ func main() {
 		apiURL: env.GetString("EXTERNAL_URL", "localhost:3000"),
 		mail: mailConfig{
 			exp:       time.Hour * 24 * 3,
+			resetExp:  time.Hour,
 			fromEmail: env.GetString("FROM_EMAIL", "hello@demomailtrap.co"),
 			mailTrap: mailTrapConfig{
 				apiKey: env.GetString("MAILTRAP_API_KEY", "")},
 			sendGrid: sendGridConfig{
 				apiKey: env.GetString("SENDGRID_API_KEY", "defaultKey")}},
 		frontendURL: env.GetString("FRONTEND_URL", "http://localhost:5173"),
+		trustProxy:  env.GetBool("TRUST_PROXY", false),
 		auth: authConfig{
 			basic: basicConfig{
 				user: env.GetString("AUTH_BASIC_USER", "admin"),
 				pass: env.GetString("AUTH_BASIC_PASS", "admin")},
 			token: tokenConfig{
-				secret: env.GetString("AUTH_TOKEN_SECRET", "example"),
-				exp:    time.Hour * 24 * 3, // 3 days
-				iss:    "Social",
+				secret:     env.GetString("AUTH_TOKEN_SECRET", "example"),
+				exp:        time.Minute * 15,
+				refreshExp: time.Hour * 24 * 7, // 7 days
+				iss:        "Social",
+
+				keyID:            env.GetString("AUTH_TOKEN_KEY_ID", ""),
+				signingKeyFile:   env.GetString("AUTH_TOKEN_SIGNING_KEY_FILE", ""),
+				verificationKeys: env.GetString("AUTH_TOKEN_VERIFICATION_KEYS", ""),
 			}},
 		redis: redisConfig{
 			address: env.GetString("REDIS_ADDR", "localhost:6379"),
@@ -76,7 +85,37 @@ func main() {
 			RequestsPerTimeFrame: env.GetInt("RATE_LIMITER_REQUESTS_COUNT", 20),
 			TimeFrame:            time.Second * 5,
 			Enabled:              env.GetBool("RATE_LIMITER_ENABLED", true),
+		},
+		loginThrottle: ratelimiter.LoginConfig{
+			MaxAccountAttempts: env.GetInt("LOGIN_MAX_ACCOUNT_ATTEMPTS", 5),
+			MaxIPAttempts:      env.GetInt("LOGIN_MAX_IP_ATTEMPTS", 20),
+			Window:             time.Minute * 15,
+			BaseLockout:        time.Minute,
+			MaxLockout:         time.Hour,
+			NotifyUser:         env.GetBool("LOGIN_ALERT_EMAIL_ENABLED", true),
+			Enabled:            env.GetBool("LOGIN_THROTTLE_ENABLED", true),
+		},
+		janitor: janitorConfig{
+			interval:          env.GetDuration("JANITOR_INTERVAL", time.Hour),
+			inactiveUserGrace: env.GetDuration("INACTIVE_USER_GRACE_PERIOD", time.Hour*24*7),
+			deletionGrace:     env.GetDuration("ACCOUNT_DELETION_GRACE_PERIOD", time.Hour*24*30),
+			deletionPolicy:    env.GetString("ACCOUNT_DELETION_POLICY", deletionPolicyDelete),
+			enabled:           env.GetBool("JANITOR_ENABLED", true),
+		},
+		export: exportConfig{
+			dir:          env.GetString("EXPORT_DIR", "./exports"),
+			exp:          env.GetDuration("EXPORT_LINK_EXP", time.Hour*24*7),
+			minInterval:  env.GetDuration("EXPORT_MIN_INTERVAL", time.Hour*24),
+			pollInterval: env.GetDuration("EXPORT_POLL_INTERVAL", time.Second*30),
+			enabled:      env.GetBool("EXPORT_WORKER_ENABLED", true),
+		},
+		scheduler: schedulerConfig{
+			interval: env.GetDuration("SCHEDULER_INTERVAL", time.Second*30),
+			enabled:  env.GetBool("SCHEDULER_ENABLED", true),
 		}}
+	cfg.auth.oidc = oidcConfigsFromEnv(cfg.apiURL)
+	cfg.export.downloadURL = env.GetString("EXPORT_DOWNLOAD_URL",
+		fmt.Sprintf("http://%s/v1/exports", cfg.apiURL))
 
 	//Logger
 	logCfg := zap.NewProductionConfig()
@@ -85,6 +124,13 @@ func main() {
 	logger := zap.Must(logCfg.Build()).Sugar()
 	defer logger.Sync()
 
+	//Unknown policy must not purge accounts in unexpected way
+	switch cfg.janitor.deletionPolicy {
+	case deletionPolicyDelete, deletionPolicyAnonymize:
+	default:
+		logger.Fatalf("unknown ACCOUNT_DELETION_POLICY %q", cfg.janitor.deletionPolicy)
+	}
+
 	//DB
 	db, err := db.New(
 		cfg.db.addr,
@@ -121,10 +167,23 @@ func main() {
 	}
 
 	//auth
-	auth := auth.NewJWTAuthenticator(
-		cfg.auth.token.secret,
-		cfg.auth.token.iss,
-		cfg.auth.token.iss)
+	var authenticator auth.Authenticator
+	if cfg.auth.token.signingKeyFile == "" {
+		authenticator = auth.NewJWTAuthenticator(
+			cfg.auth.token.secret,
+			cfg.auth.token.iss,
+			cfg.auth.token.iss)
+	} else {
+		authenticator, err = newKeySetAuthenticator(cfg.auth.token)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	oidcProviders := make(map[string]*auth.OIDCProvider, len(cfg.auth.oidc))
+	for _, oidcCfg := range cfg.auth.oidc {
+		oidcProviders[oidcCfg.Name] = auth.NewOIDCProvider(oidcCfg, nil)
+	}
 
 	//Rate limiter
 	rateLimiter := ratelimiter.NewFixedWindowLimiter(
@@ -132,14 +191,24 @@ func main() {
 		cfg.rateLimiter.TimeFrame,
 	)
 
+	//Failed logins are shared between instances when redis is enabled
+	var loginThrottler ratelimiter.LoginThrottler
+	if cfg.redis.enabled {
+		loginThrottler = ratelimiter.NewRedisLoginThrottler(redis, cfg.loginThrottle)
+	} else {
+		loginThrottler = ratelimiter.NewMemoryLoginThrottler(cfg.loginThrottle)
+	}
+
 	app := application{
-		config:        cfg,
-		store:         store,
-		cacheStorage:  cacheStorage,
-		logger:        logger,
-		mailer:        mailer,
-		authenticator: auth,
-		rateLimiter:   rateLimiter,
+		config:         cfg,
+		store:          store,
+		cacheStorage:   cacheStorage,
+		logger:         logger,
+		mailer:         mailer,
+		authenticator:  authenticator,
+		rateLimiter:    rateLimiter,
+		loginThrottler: loginThrottler,
+		oidcProviders:  oidcProviders,
 	}
 
 	//Metrics collected
@@ -154,3 +223,53 @@ func main() {
 	mux := app.mount()
 	logger.Fatal(app.run(mux))
 }
+
+func newKeySetAuthenticator(cfg tokenConfig) (*auth.KeySetAuthenticator, error) {
+	signingKey, err := auth.LoadPrivateKeyFile(cfg.keyID, cfg.signingKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+
+	var verificationKeys []*auth.Key
+	for _, entry := range strings.Split(cfg.verificationKeys, ",") {
+		if entry == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("verification key %q should be in kid=path format", entry)
+		}
+		key, err := auth.LoadPublicKeyFile(kid, path)
+		if err != nil {
+			return nil, fmt.Errorf("verification key %q: %w", kid, err)
+		}
+		verificationKeys = append(verificationKeys, key)
+	}
+
+	return auth.NewKeySetAuthenticator(signingKey, verificationKeys, cfg.iss, cfg.iss)
+}
+
+// oidcConfigsFromEnv reads providers listed in OIDC_PROVIDERS="google,gitlab".
+// Every provider is configured by OIDC_<NAME>_ISSUER_URL, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET and optional OIDC_<NAME>_REDIRECT_URL
+func oidcConfigsFromEnv(apiURL string) []auth.OIDCConfig {
+	var configs []auth.OIDCConfig
+	for _, name := range strings.Split(env.GetString("OIDC_PROVIDERS", ""), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		configs = append(configs, auth.OIDCConfig{
+			Name:         name,
+			IssuerURL:    env.GetString(prefix+"ISSUER_URL", ""),
+			ClientID:     env.GetString(prefix+"CLIENT_ID", ""),
+			ClientSecret: env.GetString(prefix+"CLIENT_SECRET", ""),
+			RedirectURL: env.GetString(prefix+"REDIRECT_URL",
+				fmt.Sprintf("http://%s/v1/authentication/oidc/%s/callback", apiURL, name)),
+		})
+	}
+
+	return configs
+}
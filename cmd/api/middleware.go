@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -31,6 +33,11 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 		}
 
 		token := parts[1]
+		if strings.HasPrefix(token, personalTokenPrefix) {
+			app.personalTokenAuth(w, r, next, token)
+			return
+		}
+
 		//get user info from token
 		jwtToken, err := app.authenticator.ValidateToken(token)
 		if err != nil {
@@ -39,22 +46,120 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 		}
 
 		claims, _ := jwtToken.Claims.(jwt.MapClaims)
-		userId, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
+		//Tokens issued before "typ" claim was added are access tokens
+		if typ, ok := claims["typ"]; ok && typ != accessTokenType {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("unexpected token type %v", typ))
+			return
+		}
+
+		userId, err := userIDFromClaims(claims)
 		if err != nil {
 			app.unauthorizedErrorResponse(w, r, err)
 			return
 		}
 
-		user, err := app.getUser(r.Context(), userId)
+		ctx := r.Context()
+		//Tokens issued before sessions were added have no "jti"
+		if sessionID, _ := claims["jti"].(string); sessionID != "" {
+			if err := app.checkSession(ctx, userId, sessionID); err != nil {
+				switch {
+				case errors.Is(err, errSessionRevoked):
+					app.unauthorizedErrorResponse(w, r, err)
+				default:
+					app.internalServerError(w, r, err)
+				}
+				return
+			}
+			ctx = context.WithValue(ctx, sessionCtx, sessionID)
+		}
+
+		user, err := app.getUser(ctx, userId)
 		if err != nil {
 			app.unauthorizedErrorResponse(w, r, err)
 			return
 		}
-		ctx := context.WithValue(r.Context(), userCtx, user)
+		if user.Suspension.Active() {
+			app.accountSuspendedResponse(w, r, user.Suspension)
+			return
+		}
+		ctx = context.WithValue(ctx, userCtx, user)
+
+		//Impersonation token has the admin in "act" claim
+		if act, ok := claims["act"].(map[string]any); ok {
+			actor, err := app.impersonationActor(ctx, act)
+			if err != nil {
+				app.unauthorizedErrorResponse(w, r, err)
+				return
+			}
+			ctx = context.WithValue(ctx, actorCtx, actor)
+			app.serveImpersonated(w, r.WithContext(ctx), next)
+			return
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// personalTokenAuth authenticates bots and integrations. Request gets
+// scopes of the token, which are checked by RequireScope
+func (app *application) personalTokenAuth(w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	pt, err := app.store.PersonalTokens.Authenticate(r.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.getUser(r.Context(), pt.UserID)
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+	if user.Suspension.Active() {
+		app.accountSuspendedResponse(w, r, user.Suspension)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), userCtx, user)
+	ctx = context.WithValue(ctx, scopesCtx, pt.Scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireScope limits routes that personal access tokens can call.
+// Requests with JWT are made by user himself and have all scopes
+func (app *application) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isPersonalTokenRequest(r) && !slices.Contains(getScopesFromCtx(r), scope) {
+				app.forbiddenRepsonse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SessionOnlyMiddleware protects account management(password, tokens etc.)
+// from personal access tokens and impersonation
+func (app *application) SessionOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPersonalTokenRequest(r) || getActorFromCtx(r) != nil {
+			app.forbiddenRepsonse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func userIDFromClaims(claims jwt.MapClaims) (int64, error) {
+	return strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
+}
+
 func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 
 	return func(next http.Handler) http.Handler {
@@ -103,7 +208,10 @@ func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 	}
 }
 
-func (app *application) CheckPostOwnership(role string, next http.HandlerFunc) http.HandlerFunc {
+// CheckPostOwnership allows the author of the post or the user with the permission.
+// Draft and scheduled posts are visible only to the author, so the permission
+// does not give access to them
+func (app *application) CheckPostOwnership(permission string, next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := getUserFromCtx(r)
 		post := getPostFromCtx(r)
@@ -112,8 +220,12 @@ func (app *application) CheckPostOwnership(role string, next http.HandlerFunc) h
 			next.ServeHTTP(w, r)
 			return
 		}
+		if post.Status != store.PostPublished {
+			app.notFoundResponse(w, r, store.ErrNotFound)
+			return
+		}
 
-		allowed, err := app.checkRolePrecedence(r.Context(), user, role)
+		allowed, err := app.hasPermission(r.Context(), user, permission)
 		if err != nil {
 			app.internalServerError(w, r, err)
 			return
@@ -126,13 +238,54 @@ func (app *application) CheckPostOwnership(role string, next http.HandlerFunc) h
 	})
 }
 
-func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
-	role, err := app.store.Roles.GetByName(ctx, roleName)
+// RequirePermission allows the route only to users whose role has the permission
+func (app *application) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, err := app.hasPermission(r.Context(), getUserFromCtx(r), permission)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+			if !allowed {
+				app.forbiddenRepsonse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (app *application) hasPermission(ctx context.Context, user *store.User, permission string) (bool, error) {
+	role, err := app.getRole(ctx, user.Role.ID)
 	if err != nil {
 		return false, err
 	}
 
-	return user.Role.Level >= role.Level, nil
+	return slices.Contains(role.Permissions, permission), nil
+}
+
+func (app *application) getRole(ctx context.Context, roleID int64) (*store.Role, error) {
+	if !app.config.redis.enabled {
+		return app.store.Roles.GetByID(ctx, roleID)
+	}
+
+	role, err := app.cacheStorage.Roles.Get(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == nil {
+		role, err = app.store.Roles.GetByID(ctx, roleID)
+		if err != nil {
+			return nil, err
+		}
+		if err := app.cacheStorage.Roles.Set(ctx, role); err != nil {
+			app.logger.Warnw("Role was not updated in cache", "role_id", roleID, "err", err.Error())
+		}
+	}
+	return role, nil
 }
 
 func (app *application) getUser(ctx context.Context, userID int64) (*store.User, error) {
@@ -146,7 +299,7 @@ func (app *application) getUser(ctx context.Context, userID int64) (*store.User,
 	}
 
 	if user == nil {
-		user, err := app.store.Users.GetByID(ctx, userID)
+		user, err = app.store.Users.GetByID(ctx, userID)
 		if err != nil {
 			return nil, err
 		}
@@ -158,10 +311,59 @@ func (app *application) getUser(ctx context.Context, userID int64) (*store.User,
 	return user, nil
 }
 
+// invalidateUserCache should be called after the user was changed in DB,
+// so the next request does not get outdated user from cache
+func (app *application) invalidateUserCache(ctx context.Context, userID int64) {
+	if !app.config.redis.enabled {
+		return
+	}
+
+	if err := app.cacheStorage.Users.Delete(ctx, userID); err != nil {
+		app.logger.Warnw("User was not removed from cache", "user_id", userID, "err", err.Error())
+	}
+}
+
+// invalidateSessionsCache should be called after sessions of the user were
+// created or revoked
+func (app *application) invalidateSessionsCache(ctx context.Context, userID int64) {
+	if !app.config.redis.enabled {
+		return
+	}
+
+	if err := app.cacheStorage.Sessions.Delete(ctx, userID); err != nil {
+		app.logger.Warnw("Sessions were not removed from cache", "user_id", userID, "err", err.Error())
+	}
+}
+
+// invalidateSuggestionsCache should be called after the user followed,
+// blocked or muted somebody, so suggestions do not show them
+func (app *application) invalidateSuggestionsCache(ctx context.Context, userID int64) {
+	if !app.config.redis.enabled {
+		return
+	}
+
+	if err := app.cacheStorage.Suggestions.Delete(ctx, userID); err != nil {
+		app.logger.Warnw("Suggestions were not removed from cache", "user_id", userID, "err", err.Error())
+	}
+}
+
+// invalidateRoleCache should be called after permissions of the role were changed
+func (app *application) invalidateRoleCache(ctx context.Context, roleID int64) {
+	if !app.config.redis.enabled {
+		return
+	}
+
+	if err := app.cacheStorage.Roles.Delete(ctx, roleID); err != nil {
+		app.logger.Warnw("Role was not removed from cache", "role_id", roleID, "err", err.Error())
+	}
+}
+
+// RateLimiterMiddleware limits requests per client IP. Port is not a part of
+// the key, otherwise every new connection would get a new limit
 func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if app.config.rateLimiter.Enabled {
-			if allow, retryAfter := app.rateLimiter.Allow(r.RemoteAddr); !allow {
+			if allow, retryAfter := app.rateLimiter.Allow(clientIP(r)); !allow {
 				app.rateLimitExceededResponse(w, r, retryAfter.String())
 				return
 			}
@@ -7,6 +7,7 @@ package mock_auth
 import (
 	reflect "reflect"
 
+	auth "github.com/O-Nikitin/Social/internal/auth"
 	jwt "github.com/golang-jwt/jwt/v5"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -49,6 +50,20 @@ func (mr *MockAuthenticatorMockRecorder) GenerateToken(claims interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockAuthenticator)(nil).GenerateToken), claims)
 }
 
+// JWKS mocks base method.
+func (m *MockAuthenticator) JWKS() auth.JWKSet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(auth.JWKSet)
+	return ret0
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockAuthenticatorMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockAuthenticator)(nil).JWKS))
+}
+
 // ValidateToken mocks base method.
 func (m *MockAuthenticator) ValidateToken(arg0 string) (*jwt.Token, error) {
 	m.ctrl.T.Helper()
@@ -5,6 +5,7 @@
 package mock_limiter
 
 import (
+	context "context"
 	reflect "reflect"
 	time "time"
 
@@ -48,3 +49,70 @@ func (mr *MockLimiterMockRecorder) Allow(ip interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allow", reflect.TypeOf((*MockLimiter)(nil).Allow), ip)
 }
+
+// MockLoginThrottler is a mock of LoginThrottler interface.
+type MockLoginThrottler struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginThrottlerMockRecorder
+}
+
+// MockLoginThrottlerMockRecorder is the mock recorder for MockLoginThrottler.
+type MockLoginThrottlerMockRecorder struct {
+	mock *MockLoginThrottler
+}
+
+// NewMockLoginThrottler creates a new mock instance.
+func NewMockLoginThrottler(ctrl *gomock.Controller) *MockLoginThrottler {
+	mock := &MockLoginThrottler{ctrl: ctrl}
+	mock.recorder = &MockLoginThrottlerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginThrottler) EXPECT() *MockLoginThrottlerMockRecorder {
+	return m.recorder
+}
+
+// Fail mocks base method.
+func (m *MockLoginThrottler) Fail(ctx context.Context, key string, maxAttempts int) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fail", ctx, key, maxAttempts)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Fail indicates an expected call of Fail.
+func (mr *MockLoginThrottlerMockRecorder) Fail(ctx, key, maxAttempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fail", reflect.TypeOf((*MockLoginThrottler)(nil).Fail), ctx, key, maxAttempts)
+}
+
+// Locked mocks base method.
+func (m *MockLoginThrottler) Locked(ctx context.Context, key string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Locked", ctx, key)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Locked indicates an expected call of Locked.
+func (mr *MockLoginThrottlerMockRecorder) Locked(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Locked", reflect.TypeOf((*MockLoginThrottler)(nil).Locked), ctx, key)
+}
+
+// Reset mocks base method.
+func (m *MockLoginThrottler) Reset(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reset", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockLoginThrottlerMockRecorder) Reset(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockLoginThrottler)(nil).Reset), ctx, key)
+}
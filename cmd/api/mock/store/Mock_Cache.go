@@ -35,6 +35,20 @@ func (m *MockUserCache) EXPECT() *MockUserCacheMockRecorder {
 	return m.recorder
 }
 
+// Delete mocks base method.
+func (m *MockUserCache) Delete(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserCacheMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserCache)(nil).Delete), arg0, arg1)
+}
+
 // Get mocks base method.
 func (m *MockUserCache) Get(arg0 context.Context, arg1 int64) (*store.User, error) {
 	m.ctrl.T.Helper()
@@ -63,3 +77,201 @@ func (mr *MockUserCacheMockRecorder) Set(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockUserCache)(nil).Set), arg0, arg1)
 }
+
+// MockSessionCache is a mock of Sessions interface.
+type MockSessionCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionCacheMockRecorder
+}
+
+// MockSessionCacheMockRecorder is the mock recorder for MockSessionCache.
+type MockSessionCacheMockRecorder struct {
+	mock *MockSessionCache
+}
+
+// NewMockSessionCache creates a new mock instance.
+func NewMockSessionCache(ctrl *gomock.Controller) *MockSessionCache {
+	mock := &MockSessionCache{ctrl: ctrl}
+	mock.recorder = &MockSessionCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessionCache) EXPECT() *MockSessionCacheMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockSessionCache) Delete(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockSessionCacheMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockSessionCache)(nil).Delete), arg0, arg1)
+}
+
+// Get mocks base method.
+func (m *MockSessionCache) Get(arg0 context.Context, arg1 int64) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockSessionCacheMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockSessionCache)(nil).Get), arg0, arg1)
+}
+
+// Set mocks base method.
+func (m *MockSessionCache) Set(arg0 context.Context, arg1 int64, arg2 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockSessionCacheMockRecorder) Set(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockSessionCache)(nil).Set), arg0, arg1, arg2)
+}
+
+// MockSuggestionCache is a mock of Suggestions interface.
+type MockSuggestionCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockSuggestionCacheMockRecorder
+}
+
+// MockSuggestionCacheMockRecorder is the mock recorder for MockSuggestionCache.
+type MockSuggestionCacheMockRecorder struct {
+	mock *MockSuggestionCache
+}
+
+// NewMockSuggestionCache creates a new mock instance.
+func NewMockSuggestionCache(ctrl *gomock.Controller) *MockSuggestionCache {
+	mock := &MockSuggestionCache{ctrl: ctrl}
+	mock.recorder = &MockSuggestionCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSuggestionCache) EXPECT() *MockSuggestionCacheMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockSuggestionCache) Delete(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockSuggestionCacheMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockSuggestionCache)(nil).Delete), arg0, arg1)
+}
+
+// Get mocks base method.
+func (m *MockSuggestionCache) Get(arg0 context.Context, arg1 int64) ([]store.Suggestion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].([]store.Suggestion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockSuggestionCacheMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockSuggestionCache)(nil).Get), arg0, arg1)
+}
+
+// Set mocks base method.
+func (m *MockSuggestionCache) Set(arg0 context.Context, arg1 int64, arg2 []store.Suggestion) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockSuggestionCacheMockRecorder) Set(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockSuggestionCache)(nil).Set), arg0, arg1, arg2)
+}
+
+// MockRoleCache is a mock of Roles interface.
+type MockRoleCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoleCacheMockRecorder
+}
+
+// MockRoleCacheMockRecorder is the mock recorder for MockRoleCache.
+type MockRoleCacheMockRecorder struct {
+	mock *MockRoleCache
+}
+
+// NewMockRoleCache creates a new mock instance.
+func NewMockRoleCache(ctrl *gomock.Controller) *MockRoleCache {
+	mock := &MockRoleCache{ctrl: ctrl}
+	mock.recorder = &MockRoleCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoleCache) EXPECT() *MockRoleCacheMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockRoleCache) Delete(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockRoleCacheMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRoleCache)(nil).Delete), arg0, arg1)
+}
+
+// Get mocks base method.
+func (m *MockRoleCache) Get(arg0 context.Context, arg1 int64) (*store.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(*store.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockRoleCacheMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRoleCache)(nil).Get), arg0, arg1)
+}
+
+// Set mocks base method.
+func (m *MockRoleCache) Set(arg0 context.Context, arg1 *store.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockRoleCacheMockRecorder) Set(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockRoleCache)(nil).Set), arg0, arg1)
+}
@@ -80,6 +80,36 @@ func (mr *MockPostsMockRecorder) GetByID(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockPosts)(nil).GetByID), arg0, arg1)
 }
 
+// GetByUserID mocks base method.
+func (m *MockPosts) GetByUserID(arg0 context.Context, arg1 int64, arg2 store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserID", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]store.PostWithMetadata)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserID indicates an expected call of GetByUserID.
+func (mr *MockPostsMockRecorder) GetByUserID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserID", reflect.TypeOf((*MockPosts)(nil).GetByUserID), arg0, arg1, arg2)
+}
+
+// GetDrafts mocks base method.
+func (m *MockPosts) GetDrafts(arg0 context.Context, arg1 int64, arg2 store.PaginatedQuery) ([]store.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDrafts", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]store.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDrafts indicates an expected call of GetDrafts.
+func (mr *MockPostsMockRecorder) GetDrafts(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDrafts", reflect.TypeOf((*MockPosts)(nil).GetDrafts), arg0, arg1, arg2)
+}
+
 // GetUserFeed mocks base method.
 func (m *MockPosts) GetUserFeed(arg0 context.Context, arg1 int64, arg2 store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
 	m.ctrl.T.Helper()
@@ -95,18 +125,33 @@ func (mr *MockPostsMockRecorder) GetUserFeed(arg0, arg1, arg2 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserFeed", reflect.TypeOf((*MockPosts)(nil).GetUserFeed), arg0, arg1, arg2)
 }
 
+// PublishDue mocks base method.
+func (m *MockPosts) PublishDue(arg0 context.Context, arg1 int) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishDue", arg0, arg1)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PublishDue indicates an expected call of PublishDue.
+func (mr *MockPostsMockRecorder) PublishDue(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishDue", reflect.TypeOf((*MockPosts)(nil).PublishDue), arg0, arg1)
+}
+
 // UpdateByID mocks base method.
-func (m *MockPosts) UpdateByID(arg0 context.Context, arg1 *store.Post) error {
+func (m *MockPosts) UpdateByID(arg0 context.Context, arg1 *store.Post, arg2 int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateByID", arg0, arg1)
+	ret := m.ctrl.Call(m, "UpdateByID", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateByID indicates an expected call of UpdateByID.
-func (mr *MockPostsMockRecorder) UpdateByID(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockPostsMockRecorder) UpdateByID(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateByID", reflect.TypeOf((*MockPosts)(nil).UpdateByID), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateByID", reflect.TypeOf((*MockPosts)(nil).UpdateByID), arg0, arg1, arg2)
 }
 
 // MockUsers is a mock of Users interface.
@@ -146,6 +191,49 @@ func (mr *MockUsersMockRecorder) Activate(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Activate", reflect.TypeOf((*MockUsers)(nil).Activate), arg0, arg1)
 }
 
+// CancelDeletion mocks base method.
+func (m *MockUsers) CancelDeletion(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelDeletion", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelDeletion indicates an expected call of CancelDeletion.
+func (mr *MockUsersMockRecorder) CancelDeletion(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelDeletion", reflect.TypeOf((*MockUsers)(nil).CancelDeletion), arg0, arg1)
+}
+
+// ChangePassword mocks base method.
+func (m *MockUsers) ChangePassword(arg0 context.Context, arg1 *store.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockUsersMockRecorder) ChangePassword(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockUsers)(nil).ChangePassword), arg0, arg1)
+}
+
+// ConfirmEmailChange mocks base method.
+func (m *MockUsers) ConfirmEmailChange(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmEmailChange", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmEmailChange indicates an expected call of ConfirmEmailChange.
+func (mr *MockUsersMockRecorder) ConfirmEmailChange(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmEmailChange", reflect.TypeOf((*MockUsers)(nil).ConfirmEmailChange), arg0, arg1)
+}
+
 // Create mocks base method.
 func (m *MockUsers) Create(arg0 context.Context, arg1 *sql.Tx, arg2 *store.User) error {
 	m.ctrl.T.Helper()
@@ -174,6 +262,34 @@ func (mr *MockUsersMockRecorder) CreateAndInvite(arg0, arg1, arg2, arg3 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAndInvite", reflect.TypeOf((*MockUsers)(nil).CreateAndInvite), arg0, arg1, arg2, arg3)
 }
 
+// CreateEmailChange mocks base method.
+func (m *MockUsers) CreateEmailChange(arg0 context.Context, arg1 int64, arg2, arg3 string, arg4 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEmailChange", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateEmailChange indicates an expected call of CreateEmailChange.
+func (mr *MockUsersMockRecorder) CreateEmailChange(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEmailChange", reflect.TypeOf((*MockUsers)(nil).CreateEmailChange), arg0, arg1, arg2, arg3, arg4)
+}
+
+// CreatePasswordReset mocks base method.
+func (m *MockUsers) CreatePasswordReset(arg0 context.Context, arg1 int64, arg2 string, arg3 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasswordReset", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePasswordReset indicates an expected call of CreatePasswordReset.
+func (mr *MockUsersMockRecorder) CreatePasswordReset(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasswordReset", reflect.TypeOf((*MockUsers)(nil).CreatePasswordReset), arg0, arg1, arg2, arg3)
+}
+
 // Delete mocks base method.
 func (m *MockUsers) Delete(arg0 context.Context, arg1 int64) error {
 	m.ctrl.T.Helper()
@@ -188,6 +304,36 @@ func (mr *MockUsersMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUsers)(nil).Delete), arg0, arg1)
 }
 
+// DeleteExpiredInvitations mocks base method.
+func (m *MockUsers) DeleteExpiredInvitations(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredInvitations", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredInvitations indicates an expected call of DeleteExpiredInvitations.
+func (mr *MockUsersMockRecorder) DeleteExpiredInvitations(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredInvitations", reflect.TypeOf((*MockUsers)(nil).DeleteExpiredInvitations), arg0)
+}
+
+// DeleteNotActivated mocks base method.
+func (m *MockUsers) DeleteNotActivated(arg0 context.Context, arg1 time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteNotActivated", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteNotActivated indicates an expected call of DeleteNotActivated.
+func (mr *MockUsersMockRecorder) DeleteNotActivated(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNotActivated", reflect.TypeOf((*MockUsers)(nil).DeleteNotActivated), arg0, arg1)
+}
+
 // GetByEmail mocks base method.
 func (m *MockUsers) GetByEmail(arg0 context.Context, arg1 string) (*store.User, error) {
 	m.ctrl.T.Helper()
@@ -218,6 +364,93 @@ func (mr *MockUsersMockRecorder) GetByID(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockUsers)(nil).GetByID), arg0, arg1)
 }
 
+// PurgeDeleted mocks base method.
+func (m *MockUsers) PurgeDeleted(arg0 context.Context, arg1 time.Duration, arg2 bool) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockUsersMockRecorder) PurgeDeleted(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockUsers)(nil).PurgeDeleted), arg0, arg1, arg2)
+}
+
+// RenewInvitation mocks base method.
+func (m *MockUsers) RenewInvitation(arg0 context.Context, arg1, arg2 string, arg3 time.Duration) (*store.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenewInvitation", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*store.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenewInvitation indicates an expected call of RenewInvitation.
+func (mr *MockUsersMockRecorder) RenewInvitation(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewInvitation", reflect.TypeOf((*MockUsers)(nil).RenewInvitation), arg0, arg1, arg2, arg3)
+}
+
+// ResetPassword mocks base method.
+func (m *MockUsers) ResetPassword(arg0 context.Context, arg1 string, arg2 *store.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUsersMockRecorder) ResetPassword(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUsers)(nil).ResetPassword), arg0, arg1, arg2)
+}
+
+// ScheduleDeletion mocks base method.
+func (m *MockUsers) ScheduleDeletion(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleDeletion", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ScheduleDeletion indicates an expected call of ScheduleDeletion.
+func (mr *MockUsersMockRecorder) ScheduleDeletion(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleDeletion", reflect.TypeOf((*MockUsers)(nil).ScheduleDeletion), arg0, arg1)
+}
+
+// Search mocks base method.
+func (m *MockUsers) Search(arg0 context.Context, arg1 int64, arg2 store.UserSearchQuery) ([]store.UserSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]store.UserSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockUsersMockRecorder) Search(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockUsers)(nil).Search), arg0, arg1, arg2)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockUsers) UpdateProfile(arg0 context.Context, arg1 *store.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockUsersMockRecorder) UpdateProfile(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockUsers)(nil).UpdateProfile), arg0, arg1)
+}
+
 // MockComments is a mock of Comments interface.
 type MockComments struct {
 	ctrl     *gomock.Controller
@@ -255,6 +488,35 @@ func (mr *MockCommentsMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockComments)(nil).Create), arg0, arg1)
 }
 
+// Delete mocks base method.
+func (m *MockComments) Delete(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockCommentsMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockComments)(nil).Delete), arg0, arg1)
+}
+
+// GetByID mocks base method.
+func (m *MockComments) GetByID(arg0 context.Context, arg1 int64) (*store.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", arg0, arg1)
+	ret0, _ := ret[0].(*store.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockCommentsMockRecorder) GetByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockComments)(nil).GetByID), arg0, arg1)
+}
+
 // GetByPostID mocks base method.
 func (m *MockComments) GetByPostID(arg0 context.Context, arg1 int64) ([]store.Comment, error) {
 	m.ctrl.T.Helper()
@@ -293,20 +555,139 @@ func (m *MockFollowers) EXPECT() *MockFollowersMockRecorder {
 	return m.recorder
 }
 
-// Follow mocks base method.
-func (m *MockFollowers) Follow(arg0 context.Context, arg1, arg2 int64) error {
+// ApproveRequest mocks base method.
+func (m *MockFollowers) ApproveRequest(arg0 context.Context, arg1, arg2 int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Follow", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "ApproveRequest", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// ApproveRequest indicates an expected call of ApproveRequest.
+func (mr *MockFollowersMockRecorder) ApproveRequest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveRequest", reflect.TypeOf((*MockFollowers)(nil).ApproveRequest), arg0, arg1, arg2)
+}
+
+// CanSeePosts mocks base method.
+func (m *MockFollowers) CanSeePosts(arg0 context.Context, arg1, arg2 int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CanSeePosts", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CanSeePosts indicates an expected call of CanSeePosts.
+func (mr *MockFollowersMockRecorder) CanSeePosts(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CanSeePosts", reflect.TypeOf((*MockFollowers)(nil).CanSeePosts), arg0, arg1, arg2)
+}
+
+// Follow mocks base method.
+func (m *MockFollowers) Follow(arg0 context.Context, arg1, arg2 int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Follow", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // Follow indicates an expected call of Follow.
 func (mr *MockFollowersMockRecorder) Follow(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Follow", reflect.TypeOf((*MockFollowers)(nil).Follow), arg0, arg1, arg2)
 }
 
+// GetFollowers mocks base method.
+func (m *MockFollowers) GetFollowers(arg0 context.Context, arg1 int64, arg2 store.PaginatedQuery) ([]store.FollowEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowers", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]store.FollowEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowers indicates an expected call of GetFollowers.
+func (mr *MockFollowersMockRecorder) GetFollowers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowers", reflect.TypeOf((*MockFollowers)(nil).GetFollowers), arg0, arg1, arg2)
+}
+
+// GetFollowing mocks base method.
+func (m *MockFollowers) GetFollowing(arg0 context.Context, arg1 int64, arg2 store.PaginatedQuery) ([]store.FollowEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowing", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]store.FollowEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowing indicates an expected call of GetFollowing.
+func (mr *MockFollowersMockRecorder) GetFollowing(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowing", reflect.TypeOf((*MockFollowers)(nil).GetFollowing), arg0, arg1, arg2)
+}
+
+// GetRelationship mocks base method.
+func (m *MockFollowers) GetRelationship(arg0 context.Context, arg1, arg2 int64) (*store.Relationship, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRelationship", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*store.Relationship)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRelationship indicates an expected call of GetRelationship.
+func (mr *MockFollowersMockRecorder) GetRelationship(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRelationship", reflect.TypeOf((*MockFollowers)(nil).GetRelationship), arg0, arg1, arg2)
+}
+
+// GetRequests mocks base method.
+func (m *MockFollowers) GetRequests(arg0 context.Context, arg1 int64, arg2 store.PaginatedQuery) ([]store.FollowRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRequests", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]store.FollowRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRequests indicates an expected call of GetRequests.
+func (mr *MockFollowersMockRecorder) GetRequests(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRequests", reflect.TypeOf((*MockFollowers)(nil).GetRequests), arg0, arg1, arg2)
+}
+
+// GetSuggestions mocks base method.
+func (m *MockFollowers) GetSuggestions(arg0 context.Context, arg1 int64, arg2 int) ([]store.Suggestion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSuggestions", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]store.Suggestion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSuggestions indicates an expected call of GetSuggestions.
+func (mr *MockFollowersMockRecorder) GetSuggestions(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSuggestions", reflect.TypeOf((*MockFollowers)(nil).GetSuggestions), arg0, arg1, arg2)
+}
+
+// RejectRequest mocks base method.
+func (m *MockFollowers) RejectRequest(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectRequest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RejectRequest indicates an expected call of RejectRequest.
+func (mr *MockFollowersMockRecorder) RejectRequest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectRequest", reflect.TypeOf((*MockFollowers)(nil).RejectRequest), arg0, arg1, arg2)
+}
+
 // Unfollow mocks base method.
 func (m *MockFollowers) Unfollow(arg0 context.Context, arg1, arg2 int64) error {
 	m.ctrl.T.Helper()
@@ -344,17 +725,867 @@ func (m *MockRoles) EXPECT() *MockRolesMockRecorder {
 	return m.recorder
 }
 
-// GetByName mocks base method.
-func (m *MockRoles) GetByName(arg0 context.Context, arg1 string) (*store.Role, error) {
+// Assign mocks base method.
+func (m *MockRoles) Assign(arg0 context.Context, arg1 int64, arg2 string, arg3 int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByName", arg0, arg1)
+	ret := m.ctrl.Call(m, "Assign", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Assign indicates an expected call of Assign.
+func (mr *MockRolesMockRecorder) Assign(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Assign", reflect.TypeOf((*MockRoles)(nil).Assign), arg0, arg1, arg2, arg3)
+}
+
+// Create mocks base method.
+func (m *MockRoles) Create(arg0 context.Context, arg1 *store.Role, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockRolesMockRecorder) Create(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRoles)(nil).Create), arg0, arg1, arg2)
+}
+
+// GetAll mocks base method.
+func (m *MockRoles) GetAll(arg0 context.Context) ([]store.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", arg0)
+	ret0, _ := ret[0].([]store.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockRolesMockRecorder) GetAll(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockRoles)(nil).GetAll), arg0)
+}
+
+// GetByID mocks base method.
+func (m *MockRoles) GetByID(arg0 context.Context, arg1 int64) (*store.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", arg0, arg1)
 	ret0, _ := ret[0].(*store.Role)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetByName indicates an expected call of GetByName.
-func (mr *MockRolesMockRecorder) GetByName(arg0, arg1 interface{}) *gomock.Call {
+// GetByID indicates an expected call of GetByID.
+func (mr *MockRolesMockRecorder) GetByID(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockRoles)(nil).GetByName), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockRoles)(nil).GetByID), arg0, arg1)
+}
+
+// GetByName mocks base method.
+func (m *MockRoles) GetByName(arg0 context.Context, arg1 string) (*store.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByName", arg0, arg1)
+	ret0, _ := ret[0].(*store.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByName indicates an expected call of GetByName.
+func (mr *MockRolesMockRecorder) GetByName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockRoles)(nil).GetByName), arg0, arg1)
+}
+
+// SetPermissions mocks base method.
+func (m *MockRoles) SetPermissions(arg0 context.Context, arg1 *store.Role, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPermissions", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPermissions indicates an expected call of SetPermissions.
+func (mr *MockRolesMockRecorder) SetPermissions(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPermissions", reflect.TypeOf((*MockRoles)(nil).SetPermissions), arg0, arg1, arg2)
+}
+
+// MockRefreshTokens is a mock of RefreshTokens interface.
+type MockRefreshTokens struct {
+	ctrl     *gomock.Controller
+	recorder *MockRefreshTokensMockRecorder
+}
+
+// MockRefreshTokensMockRecorder is the mock recorder for MockRefreshTokens.
+type MockRefreshTokensMockRecorder struct {
+	mock *MockRefreshTokens
+}
+
+// NewMockRefreshTokens creates a new mock instance.
+func NewMockRefreshTokens(ctrl *gomock.Controller) *MockRefreshTokens {
+	mock := &MockRefreshTokens{ctrl: ctrl}
+	mock.recorder = &MockRefreshTokensMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRefreshTokens) EXPECT() *MockRefreshTokensMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockRefreshTokens) Create(arg0 context.Context, arg1 string, arg2 *store.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockRefreshTokensMockRecorder) Create(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRefreshTokens)(nil).Create), arg0, arg1, arg2)
+}
+
+// RevokeFamily mocks base method.
+func (m *MockRefreshTokens) RevokeFamily(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeFamily", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeFamily indicates an expected call of RevokeFamily.
+func (mr *MockRefreshTokensMockRecorder) RevokeFamily(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeFamily", reflect.TypeOf((*MockRefreshTokens)(nil).RevokeFamily), arg0, arg1)
+}
+
+// Rotate mocks base method.
+func (m *MockRefreshTokens) Rotate(arg0 context.Context, arg1, arg2 string, arg3 time.Duration) (*store.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rotate", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*store.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rotate indicates an expected call of Rotate.
+func (mr *MockRefreshTokensMockRecorder) Rotate(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rotate", reflect.TypeOf((*MockRefreshTokens)(nil).Rotate), arg0, arg1, arg2, arg3)
+}
+
+// MockSessions is a mock of Sessions interface.
+type MockSessions struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionsMockRecorder
+}
+
+// MockSessionsMockRecorder is the mock recorder for MockSessions.
+type MockSessionsMockRecorder struct {
+	mock *MockSessions
+}
+
+// NewMockSessions creates a new mock instance.
+func NewMockSessions(ctrl *gomock.Controller) *MockSessions {
+	mock := &MockSessions{ctrl: ctrl}
+	mock.recorder = &MockSessionsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessions) EXPECT() *MockSessionsMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockSessions) Create(arg0 context.Context, arg1 *store.Session, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockSessionsMockRecorder) Create(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockSessions)(nil).Create), arg0, arg1, arg2)
+}
+
+// Delete mocks base method.
+func (m *MockSessions) Delete(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockSessionsMockRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockSessions)(nil).Delete), arg0, arg1, arg2)
+}
+
+// DeleteOthers mocks base method.
+func (m *MockSessions) DeleteOthers(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOthers", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOthers indicates an expected call of DeleteOthers.
+func (mr *MockSessionsMockRecorder) DeleteOthers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOthers", reflect.TypeOf((*MockSessions)(nil).DeleteOthers), arg0, arg1, arg2)
+}
+
+// GetByUserID mocks base method.
+func (m *MockSessions) GetByUserID(arg0 context.Context, arg1 int64) ([]store.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserID", arg0, arg1)
+	ret0, _ := ret[0].([]store.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserID indicates an expected call of GetByUserID.
+func (mr *MockSessionsMockRecorder) GetByUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserID", reflect.TypeOf((*MockSessions)(nil).GetByUserID), arg0, arg1)
+}
+
+// LoggedInSince mocks base method.
+func (m *MockSessions) LoggedInSince(arg0 context.Context, arg1 int64, arg2 string, arg3 time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoggedInSince", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoggedInSince indicates an expected call of LoggedInSince.
+func (mr *MockSessionsMockRecorder) LoggedInSince(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoggedInSince", reflect.TypeOf((*MockSessions)(nil).LoggedInSince), arg0, arg1, arg2, arg3)
+}
+
+// Touch mocks base method.
+func (m *MockSessions) Touch(arg0 context.Context, arg1 *store.Session) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Touch", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Touch indicates an expected call of Touch.
+func (mr *MockSessionsMockRecorder) Touch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Touch", reflect.TypeOf((*MockSessions)(nil).Touch), arg0, arg1)
+}
+
+// MockTwoFactor is a mock of TwoFactor interface.
+type MockTwoFactor struct {
+	ctrl     *gomock.Controller
+	recorder *MockTwoFactorMockRecorder
+}
+
+// MockTwoFactorMockRecorder is the mock recorder for MockTwoFactor.
+type MockTwoFactorMockRecorder struct {
+	mock *MockTwoFactor
+}
+
+// NewMockTwoFactor creates a new mock instance.
+func NewMockTwoFactor(ctrl *gomock.Controller) *MockTwoFactor {
+	mock := &MockTwoFactor{ctrl: ctrl}
+	mock.recorder = &MockTwoFactorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTwoFactor) EXPECT() *MockTwoFactorMockRecorder {
+	return m.recorder
+}
+
+// Confirm mocks base method.
+func (m *MockTwoFactor) Confirm(arg0 context.Context, arg1, arg2 int64, arg3 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Confirm", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Confirm indicates an expected call of Confirm.
+func (mr *MockTwoFactorMockRecorder) Confirm(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Confirm", reflect.TypeOf((*MockTwoFactor)(nil).Confirm), arg0, arg1, arg2, arg3)
+}
+
+// Disable mocks base method.
+func (m *MockTwoFactor) Disable(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockTwoFactorMockRecorder) Disable(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockTwoFactor)(nil).Disable), arg0, arg1)
+}
+
+// Enroll mocks base method.
+func (m *MockTwoFactor) Enroll(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enroll", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enroll indicates an expected call of Enroll.
+func (mr *MockTwoFactorMockRecorder) Enroll(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enroll", reflect.TypeOf((*MockTwoFactor)(nil).Enroll), arg0, arg1, arg2)
+}
+
+// Get mocks base method.
+func (m *MockTwoFactor) Get(arg0 context.Context, arg1 int64) (*store.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(*store.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockTwoFactorMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockTwoFactor)(nil).Get), arg0, arg1)
+}
+
+// UseCode mocks base method.
+func (m *MockTwoFactor) UseCode(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseCode", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseCode indicates an expected call of UseCode.
+func (mr *MockTwoFactorMockRecorder) UseCode(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseCode", reflect.TypeOf((*MockTwoFactor)(nil).UseCode), arg0, arg1, arg2)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockTwoFactor) UseRecoveryCode(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockTwoFactorMockRecorder) UseRecoveryCode(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockTwoFactor)(nil).UseRecoveryCode), arg0, arg1, arg2)
+}
+
+// MockPersonalTokens is a mock of PersonalTokens interface.
+type MockPersonalTokens struct {
+	ctrl     *gomock.Controller
+	recorder *MockPersonalTokensMockRecorder
+}
+
+// MockPersonalTokensMockRecorder is the mock recorder for MockPersonalTokens.
+type MockPersonalTokensMockRecorder struct {
+	mock *MockPersonalTokens
+}
+
+// NewMockPersonalTokens creates a new mock instance.
+func NewMockPersonalTokens(ctrl *gomock.Controller) *MockPersonalTokens {
+	mock := &MockPersonalTokens{ctrl: ctrl}
+	mock.recorder = &MockPersonalTokensMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPersonalTokens) EXPECT() *MockPersonalTokensMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockPersonalTokens) Authenticate(arg0 context.Context, arg1 string) (*store.PersonalToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", arg0, arg1)
+	ret0, _ := ret[0].(*store.PersonalToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockPersonalTokensMockRecorder) Authenticate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockPersonalTokens)(nil).Authenticate), arg0, arg1)
+}
+
+// Create mocks base method.
+func (m *MockPersonalTokens) Create(arg0 context.Context, arg1 string, arg2 *store.PersonalToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockPersonalTokensMockRecorder) Create(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockPersonalTokens)(nil).Create), arg0, arg1, arg2)
+}
+
+// Delete mocks base method.
+func (m *MockPersonalTokens) Delete(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockPersonalTokensMockRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPersonalTokens)(nil).Delete), arg0, arg1, arg2)
+}
+
+// GetByUserID mocks base method.
+func (m *MockPersonalTokens) GetByUserID(arg0 context.Context, arg1 int64) ([]store.PersonalToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserID", arg0, arg1)
+	ret0, _ := ret[0].([]store.PersonalToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserID indicates an expected call of GetByUserID.
+func (mr *MockPersonalTokensMockRecorder) GetByUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserID", reflect.TypeOf((*MockPersonalTokens)(nil).GetByUserID), arg0, arg1)
+}
+
+// MockIdentities is a mock of Identities interface.
+type MockIdentities struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdentitiesMockRecorder
+}
+
+// MockIdentitiesMockRecorder is the mock recorder for MockIdentities.
+type MockIdentitiesMockRecorder struct {
+	mock *MockIdentities
+}
+
+// NewMockIdentities creates a new mock instance.
+func NewMockIdentities(ctrl *gomock.Controller) *MockIdentities {
+	mock := &MockIdentities{ctrl: ctrl}
+	mock.recorder = &MockIdentitiesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdentities) EXPECT() *MockIdentitiesMockRecorder {
+	return m.recorder
+}
+
+// FindOrCreateUser mocks base method.
+func (m *MockIdentities) FindOrCreateUser(arg0 context.Context, arg1 *store.Identity, arg2 *store.User, arg3 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrCreateUser", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FindOrCreateUser indicates an expected call of FindOrCreateUser.
+func (mr *MockIdentitiesMockRecorder) FindOrCreateUser(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrCreateUser", reflect.TypeOf((*MockIdentities)(nil).FindOrCreateUser), arg0, arg1, arg2, arg3)
+}
+
+// MockBlocks is a mock of Blocks interface.
+type MockBlocks struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlocksMockRecorder
+}
+
+// MockBlocksMockRecorder is the mock recorder for MockBlocks.
+type MockBlocksMockRecorder struct {
+	mock *MockBlocks
+}
+
+// NewMockBlocks creates a new mock instance.
+func NewMockBlocks(ctrl *gomock.Controller) *MockBlocks {
+	mock := &MockBlocks{ctrl: ctrl}
+	mock.recorder = &MockBlocksMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlocks) EXPECT() *MockBlocksMockRecorder {
+	return m.recorder
+}
+
+// Block mocks base method.
+func (m *MockBlocks) Block(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Block", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Block indicates an expected call of Block.
+func (mr *MockBlocksMockRecorder) Block(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Block", reflect.TypeOf((*MockBlocks)(nil).Block), arg0, arg1, arg2)
+}
+
+// IsBlocked mocks base method.
+func (m *MockBlocks) IsBlocked(arg0 context.Context, arg1, arg2 int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsBlocked", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsBlocked indicates an expected call of IsBlocked.
+func (mr *MockBlocksMockRecorder) IsBlocked(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsBlocked", reflect.TypeOf((*MockBlocks)(nil).IsBlocked), arg0, arg1, arg2)
+}
+
+// Mute mocks base method.
+func (m *MockBlocks) Mute(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Mute", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Mute indicates an expected call of Mute.
+func (mr *MockBlocksMockRecorder) Mute(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mute", reflect.TypeOf((*MockBlocks)(nil).Mute), arg0, arg1, arg2)
+}
+
+// Unblock mocks base method.
+func (m *MockBlocks) Unblock(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unblock", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unblock indicates an expected call of Unblock.
+func (mr *MockBlocksMockRecorder) Unblock(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unblock", reflect.TypeOf((*MockBlocks)(nil).Unblock), arg0, arg1, arg2)
+}
+
+// Unmute mocks base method.
+func (m *MockBlocks) Unmute(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unmute", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unmute indicates an expected call of Unmute.
+func (mr *MockBlocksMockRecorder) Unmute(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unmute", reflect.TypeOf((*MockBlocks)(nil).Unmute), arg0, arg1, arg2)
+}
+
+// MockDataExports is a mock of DataExports interface.
+type MockDataExports struct {
+	ctrl     *gomock.Controller
+	recorder *MockDataExportsMockRecorder
+}
+
+// MockDataExportsMockRecorder is the mock recorder for MockDataExports.
+type MockDataExportsMockRecorder struct {
+	mock *MockDataExports
+}
+
+// NewMockDataExports creates a new mock instance.
+func NewMockDataExports(ctrl *gomock.Controller) *MockDataExports {
+	mock := &MockDataExports{ctrl: ctrl}
+	mock.recorder = &MockDataExportsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDataExports) EXPECT() *MockDataExportsMockRecorder {
+	return m.recorder
+}
+
+// ClaimPending mocks base method.
+func (m *MockDataExports) ClaimPending(arg0 context.Context) (*store.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimPending", arg0)
+	ret0, _ := ret[0].(*store.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimPending indicates an expected call of ClaimPending.
+func (mr *MockDataExportsMockRecorder) ClaimPending(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimPending", reflect.TypeOf((*MockDataExports)(nil).ClaimPending), arg0)
+}
+
+// Collect mocks base method.
+func (m *MockDataExports) Collect(arg0 context.Context, arg1 int64) (*store.UserData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Collect", arg0, arg1)
+	ret0, _ := ret[0].(*store.UserData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Collect indicates an expected call of Collect.
+func (mr *MockDataExportsMockRecorder) Collect(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collect", reflect.TypeOf((*MockDataExports)(nil).Collect), arg0, arg1)
+}
+
+// Create mocks base method.
+func (m *MockDataExports) Create(arg0 context.Context, arg1 *store.DataExport, arg2 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockDataExportsMockRecorder) Create(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockDataExports)(nil).Create), arg0, arg1, arg2)
+}
+
+// Finish mocks base method.
+func (m *MockDataExports) Finish(arg0 context.Context, arg1 *store.DataExport, arg2 string, arg3 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Finish", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Finish indicates an expected call of Finish.
+func (mr *MockDataExportsMockRecorder) Finish(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Finish", reflect.TypeOf((*MockDataExports)(nil).Finish), arg0, arg1, arg2, arg3)
+}
+
+// GetByToken mocks base method.
+func (m *MockDataExports) GetByToken(arg0 context.Context, arg1 string) (*store.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByToken", arg0, arg1)
+	ret0, _ := ret[0].(*store.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByToken indicates an expected call of GetByToken.
+func (mr *MockDataExportsMockRecorder) GetByToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByToken", reflect.TypeOf((*MockDataExports)(nil).GetByToken), arg0, arg1)
+}
+
+// MockSuspensions is a mock of Suspensions interface.
+type MockSuspensions struct {
+	ctrl     *gomock.Controller
+	recorder *MockSuspensionsMockRecorder
+}
+
+// MockSuspensionsMockRecorder is the mock recorder for MockSuspensions.
+type MockSuspensionsMockRecorder struct {
+	mock *MockSuspensions
+}
+
+// NewMockSuspensions creates a new mock instance.
+func NewMockSuspensions(ctrl *gomock.Controller) *MockSuspensions {
+	mock := &MockSuspensions{ctrl: ctrl}
+	mock.recorder = &MockSuspensionsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSuspensions) EXPECT() *MockSuspensionsMockRecorder {
+	return m.recorder
+}
+
+// GetActive mocks base method.
+func (m *MockSuspensions) GetActive(arg0 context.Context, arg1 int64) (*store.Suspension, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActive", arg0, arg1)
+	ret0, _ := ret[0].(*store.Suspension)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActive indicates an expected call of GetActive.
+func (mr *MockSuspensionsMockRecorder) GetActive(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActive", reflect.TypeOf((*MockSuspensions)(nil).GetActive), arg0, arg1)
+}
+
+// GetHistory mocks base method.
+func (m *MockSuspensions) GetHistory(arg0 context.Context, arg1 int64) ([]store.Suspension, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistory", arg0, arg1)
+	ret0, _ := ret[0].([]store.Suspension)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistory indicates an expected call of GetHistory.
+func (mr *MockSuspensionsMockRecorder) GetHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockSuspensions)(nil).GetHistory), arg0, arg1)
+}
+
+// Lift mocks base method.
+func (m *MockSuspensions) Lift(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lift", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Lift indicates an expected call of Lift.
+func (mr *MockSuspensionsMockRecorder) Lift(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lift", reflect.TypeOf((*MockSuspensions)(nil).Lift), arg0, arg1, arg2)
+}
+
+// Suspend mocks base method.
+func (m *MockSuspensions) Suspend(arg0 context.Context, arg1 *store.Suspension) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Suspend", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Suspend indicates an expected call of Suspend.
+func (mr *MockSuspensionsMockRecorder) Suspend(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suspend", reflect.TypeOf((*MockSuspensions)(nil).Suspend), arg0, arg1)
+}
+
+// MockAudit is a mock of Audit interface.
+type MockAudit struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditMockRecorder
+}
+
+// MockAuditMockRecorder is the mock recorder for MockAudit.
+type MockAuditMockRecorder struct {
+	mock *MockAudit
+}
+
+// NewMockAudit creates a new mock instance.
+func NewMockAudit(ctrl *gomock.Controller) *MockAudit {
+	mock := &MockAudit{ctrl: ctrl}
+	mock.recorder = &MockAuditMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAudit) EXPECT() *MockAuditMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockAudit) Create(arg0 context.Context, arg1 *store.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockAuditMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAudit)(nil).Create), arg0, arg1)
+}
+
+// GetAll mocks base method.
+func (m *MockAudit) GetAll(arg0 context.Context, arg1 store.PaginatedQuery) ([]store.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", arg0, arg1)
+	ret0, _ := ret[0].([]store.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockAuditMockRecorder) GetAll(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockAudit)(nil).GetAll), arg0, arg1)
+}
+
+// MockPostRevisions is a mock of PostRevisions interface.
+type MockPostRevisions struct {
+	ctrl     *gomock.Controller
+	recorder *MockPostRevisionsMockRecorder
+}
+
+// MockPostRevisionsMockRecorder is the mock recorder for MockPostRevisions.
+type MockPostRevisionsMockRecorder struct {
+	mock *MockPostRevisions
+}
+
+// NewMockPostRevisions creates a new mock instance.
+func NewMockPostRevisions(ctrl *gomock.Controller) *MockPostRevisions {
+	mock := &MockPostRevisions{ctrl: ctrl}
+	mock.recorder = &MockPostRevisionsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPostRevisions) EXPECT() *MockPostRevisionsMockRecorder {
+	return m.recorder
+}
+
+// GetByPostID mocks base method.
+func (m *MockPostRevisions) GetByPostID(arg0 context.Context, arg1 int64, arg2 store.PaginatedQuery) ([]store.PostRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByPostID", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]store.PostRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByPostID indicates an expected call of GetByPostID.
+func (mr *MockPostRevisionsMockRecorder) GetByPostID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByPostID", reflect.TypeOf((*MockPostRevisions)(nil).GetByPostID), arg0, arg1, arg2)
+}
+
+// GetByVersion mocks base method.
+func (m *MockPostRevisions) GetByVersion(arg0 context.Context, arg1 int64, arg2 int) (*store.PostRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByVersion", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*store.PostRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByVersion indicates an expected call of GetByVersion.
+func (mr *MockPostRevisionsMockRecorder) GetByVersion(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByVersion", reflect.TypeOf((*MockPostRevisions)(nil).GetByVersion), arg0, arg1, arg2)
 }
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/O-Nikitin/Social/internal/auth"
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	oidcCookieName = "oidc_login"
+	oidcCookiePath = "/v1/authentication/oidc"
+	// Time the user has to login on the provider page
+	oidcCookieMaxAge = 600
+	// Username of the provider can be taken already
+	oidcUsernameAttempts = 3
+	maxUsernameLen       = 100
+)
+
+var (
+	errInvalidOIDCState = errors.New("invalid or expired login state")
+	errNoOIDCEmail      = errors.New("identity provider did not return email")
+)
+
+// OIDCLogin godoc
+//
+//	@Summary		Sign in with external provider
+//	@Description	Redirects to the login page of OpenID Connect provider. After login the provider redirects back to the callback
+//	@Tags			authentication
+//	@Param			provider	path	string	true	"Provider name from config"
+//	@Success		302			"Redirect to the provider"
+//	@Failure		404			{object}	main.envelopeErr
+//	@Failure		500			{object}	main.envelopeErr
+//	@Router			/authentication/oidc/{provider} [get]
+func (app *application) oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	provider, ok := app.oidcProviders[chi.URLParam(r, "provider")]
+	if !ok {
+		app.notFoundResponse(w, r, fmt.Errorf("unknown identity provider %q", chi.URLParam(r, "provider")))
+		return
+	}
+
+	login, err := auth.NewOIDCLogin()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), login)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	//Values are kept by the browser, so any instance of the API can handle the callback
+	app.setOIDCCookie(w, strings.Join([]string{login.State, login.Nonce, login.CodeVerifier}, "."), oidcCookieMaxAge)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback godoc
+//
+//	@Summary		External provider callback
+//	@Description	Finishes sign in with OpenID Connect provider. The identity is linked to the user with the same verified email,
+//	@Description	otherwise a new activated user is created. First login with not verified email is rejected
+//	@Tags			authentication
+//	@Produce		json
+//	@Param			provider	path		string	true	"Provider name from config"
+//	@Param			code		query		string	true	"Authorization code"
+//	@Param			state		query		string	true	"State from the login redirect"
+//	@Success		201			{object}	main.envelopeSuccess{data=main.TokenPair}
+//	@Success		202			{object}	main.envelopeSuccess{data=main.TwoFactorChallenge}	"2FA is enabled, continue with /authentication/token/2fa"
+//	@Failure		400			{object}	main.envelopeErr
+//	@Failure		401			{object}	main.envelopeErr
+//	@Failure		404			{object}	main.envelopeErr
+//	@Failure		409			{object}	main.envelopeErr
+//	@Failure		500			{object}	main.envelopeErr
+//	@Router			/authentication/oidc/{provider}/callback [get]
+func (app *application) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	provider, ok := app.oidcProviders[chi.URLParam(r, "provider")]
+	if !ok {
+		app.notFoundResponse(w, r, fmt.Errorf("unknown identity provider %q", chi.URLParam(r, "provider")))
+		return
+	}
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		app.badRequestResponse(w, r, fmt.Errorf("identity provider error: %s", providerErr))
+		return
+	}
+
+	login, err := readOIDCCookie(r)
+	//State can be used only once
+	app.setOIDCCookie(w, "", -1)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(login.State)) != 1 {
+		app.badRequestResponse(w, r, errInvalidOIDCState)
+		return
+	}
+
+	claims, err := provider.Exchange(r.Context(), query.Get("code"), login)
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	user, err := app.oidcUser(r.Context(), provider.Name(), claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, errNoOIDCEmail), errors.Is(err, store.ErrEmailNotVerified):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, store.ErrDuplicateEmail):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.completeLogin(w, r, user)
+}
+
+// oidcUser returns the user linked to the provider account and creates
+// a new one on the first login
+func (app *application) oidcUser(
+	ctx context.Context, provider string, claims *auth.IDTokenClaims) (*store.User, error) {
+
+	if claims.Email == "" {
+		return nil, errNoOIDCEmail
+	}
+
+	identity := &store.Identity{
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	baseUsername := oidcUsername(claims)
+	for attempt := range oidcUsernameAttempts {
+		user := &store.User{
+			Username: baseUsername,
+			Email:    claims.Email,
+			Role: store.Role{
+				Name: store.UserRole,
+			},
+		}
+		if attempt > 0 {
+			suffix, err := randomHex(3)
+			if err != nil {
+				return nil, err
+			}
+			user.Username = fmt.Sprintf("%s_%s", baseUsername, suffix)
+		}
+		//The user logs in with the provider, password is needed only for the column
+		password, err := randomHex(32)
+		if err != nil {
+			return nil, err
+		}
+		if err := user.Password.Set(password); err != nil {
+			return nil, err
+		}
+
+		err = app.store.Identities.FindOrCreateUser(ctx, identity, user, claims.EmailVerified)
+		if errors.Is(err, store.ErrDuplicateUsername) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return user, nil
+	}
+
+	return nil, store.ErrDuplicateUsername
+}
+
+func oidcUsername(claims *auth.IDTokenClaims) string {
+	username := claims.PreferredUsername
+	if username == "" {
+		username = claims.Name
+	}
+	if username == "" {
+		username, _, _ = strings.Cut(claims.Email, "@")
+	}
+	//Leaves room for the suffix
+	if len(username) > maxUsernameLen-10 {
+		username = username[:maxUsernameLen-10]
+	}
+
+	return username
+}
+
+func readOIDCCookie(r *http.Request) (*auth.OIDCLogin, error) {
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		return nil, errInvalidOIDCState
+	}
+
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidOIDCState
+	}
+
+	return &auth.OIDCLogin{
+		State:        parts[0],
+		Nonce:        parts[1],
+		CodeVerifier: parts[2],
+	}, nil
+}
+
+func (app *application) setOIDCCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    value,
+		Path:     oidcCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   app.config.env == "production",
+		//Lax lets the browser send the cookie on redirect from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/O-Nikitin/Social/internal/mailer"
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/google/uuid"
+)
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,min=3,max=72"`
+}
+
+// ForgotPassword godoc
+//
+//	@Summary		Forgot password
+//	@Description	Sends an email with a one-time password reset link. Response is the same whether the email is registered or not
+//	@Tags			authentication
+//	@Accept			json
+//	@Param			payload	body	ForgotPasswordPayload	true	"User email"
+//	@Success		202		"Reset email sent if the user exists"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Router			/authentication/password/forgot [post]
+func (app *application) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ForgotPasswordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.store.Users.GetByEmail(r.Context(), payload.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			//Do not let the caller know which emails are registered
+			app.logger.Infow("password reset requested for unknown email")
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	plainToken := uuid.New().String()
+	err = app.store.Users.CreatePasswordReset(
+		r.Context(), user.ID, plainToken, app.config.mail.resetExp)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	resetURL := fmt.Sprintf(
+		"%s/reset-password/%s",
+		app.config.frontendURL,
+		plainToken)
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username string
+		ResetURL string
+		Expiry   string
+	}{
+		Username: user.Username,
+		ResetURL: resetURL,
+		Expiry:   app.config.mail.resetExp.String(),
+	}
+	code, err := app.mailer.Send(
+		mailer.PasswordResetTemplate,
+		user.Username,
+		user.Email,
+		vars,
+		!isProdEnv)
+	if err != nil {
+		app.logger.Errorw("error sending password reset email ", err.Error())
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.logger.Infow("Email sent", "status code", code)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword godoc
+//
+//	@Summary		Reset password
+//	@Description	Sets a new password using the token from the reset email. All sessions of the user are logged out
+//	@Tags			authentication
+//	@Accept			json
+//	@Param			payload	body	ResetPasswordPayload	true	"Reset token and new password"
+//	@Success		204		"Password changed"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Router			/authentication/password/reset [post]
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResetPasswordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := &store.User{}
+	if err := user.Password.Set(payload.Password); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	err := app.store.Users.ResetPassword(r.Context(), payload.Token, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.invalidateSessionsCache(r.Context(), user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
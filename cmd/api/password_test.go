@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/mailer"
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/golang/mock/gomock"
+)
+
+func TestPassword_Forgot(t *testing.T) {
+	app, mocks := newTestApp(t, config{mail: mailConfig{resetExp: time.Hour}})
+	mux := app.mount()
+	body := `{"email": "john@example.com"}`
+
+	newRequest := func(t *testing.T) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/password/forgot", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("Request not created: ", err)
+		}
+		return req
+	}
+
+	t.Run("Should_not_reveal_unknown_email",
+		func(t *testing.T) {
+			mocks.Users.EXPECT().GetByEmail(gomock.Any(), "john@example.com").Return(nil, store.ErrNotFound)
+
+			rr := executeRequest(newRequest(t), mux)
+
+			checkResponseCode(rr.Code, http.StatusAccepted, t)
+		})
+
+	t.Run("Should_send_reset_link",
+		func(t *testing.T) {
+			user := &store.User{ID: 1, Username: "john", Email: "john@example.com"}
+			mocks.Users.EXPECT().GetByEmail(gomock.Any(), "john@example.com").Return(user, nil)
+			mocks.Users.EXPECT().CreatePasswordReset(gomock.Any(), int64(1), gomock.Any(), time.Hour).Return(nil)
+			mocks.Mailer.EXPECT().
+				Send(mailer.PasswordResetTemplate, "john", "john@example.com", gomock.Any(), gomock.Any()).
+				Return(http.StatusOK, nil)
+
+			rr := executeRequest(newRequest(t), mux)
+
+			checkResponseCode(rr.Code, http.StatusAccepted, t)
+		})
+}
+
+func TestPassword_Reset(t *testing.T) {
+	app, mocks := newTestApp(t, config{redis: redisConfig{enabled: true}})
+	mux := app.mount()
+
+	newRequest := func(t *testing.T, body string) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/password/reset", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("Request not created: ", err)
+		}
+		return req
+	}
+
+	t.Run("Should_validate_new_password",
+		func(t *testing.T) {
+			rr := executeRequest(newRequest(t, `{"token": "reset-token", "password": "a"}`), mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_reject_unknown_or_expired_token",
+		func(t *testing.T) {
+			mocks.Users.EXPECT().ResetPassword(gomock.Any(), "reset-token", gomock.Any()).Return(store.ErrNotFound)
+
+			rr := executeRequest(newRequest(t, `{"token": "reset-token", "password": "new-secret"}`), mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_set_password_and_log_out_sessions",
+		func(t *testing.T) {
+			mocks.Users.EXPECT().ResetPassword(gomock.Any(), "reset-token", gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ string, u *store.User) error {
+					if err := u.Password.Compare("new-secret"); err != nil {
+						t.Errorf("expected new password to be hashed: %v", err)
+					}
+					u.ID = 1
+					return nil
+				})
+			mocks.SessCache.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+
+			rr := executeRequest(newRequest(t, `{"token": "reset-token", "password": "new-secret"}`), mux)
+
+			checkResponseCode(rr.Code, http.StatusNoContent, t)
+		})
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// personalTokenPrefix makes tokens easy to find by secret scanners
+// and lets middleware tell them apart from JWT
+const personalTokenPrefix = "social_pat_"
+
+type scopesKey string
+
+const scopesCtx scopesKey = "scopes"
+
+type CreatePersonalTokenPayload struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,scope"`
+	ExpiresInDays *int     `json:"expires_in_days" validate:"omitempty,gte=1,lte=365"`
+}
+
+type PersonalTokenWithSecret struct {
+	*store.PersonalToken
+	Token string `json:"token"`
+}
+
+// CreatePersonalToken godoc
+//
+//	@Summary		Create personal access token
+//	@Description	Creates a named token with limited scopes for bots and integrations. The token is shown only once
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreatePersonalTokenPayload	true	"Token name, scopes and expiry"
+//	@Success		201		{object}	main.envelopeSuccess{data=main.PersonalTokenWithSecret}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/tokens [post]
+func (app *application) createPersonalTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreatePersonalTokenPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	pt := &store.PersonalToken{
+		UserID: user.ID,
+		Name:   payload.Name,
+		Scopes: payload.Scopes,
+	}
+	if payload.ExpiresInDays != nil {
+		expiresAt := time.Now().Add(time.Hour * 24 * time.Duration(*payload.ExpiresInDays))
+		pt.ExpiresAt = &expiresAt
+	}
+
+	plainToken := personalTokenPrefix + strings.ReplaceAll(uuid.New().String(), "-", "")
+	if err := app.store.PersonalTokens.Create(r.Context(), plainToken, pt); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	resp := PersonalTokenWithSecret{
+		PersonalToken: pt,
+		Token:         plainToken,
+	}
+	if err := app.jsonResponse(w, http.StatusCreated, resp); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetPersonalTokens godoc
+//
+//	@Summary		List personal access tokens
+//	@Description	Lists personal access tokens of the current user without secrets
+//	@Tags			users
+//	@Produce		json
+//	@Success		200	{object}	main.envelopeSuccess{data=[]store.PersonalToken}
+//	@Failure		403	{object}	main.envelopeErr
+//	@Failure		500	{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/tokens [get]
+func (app *application) getPersonalTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	tokens, err := app.store.PersonalTokens.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeletePersonalToken godoc
+//
+//	@Summary		Revoke personal access token
+//	@Description	Revokes personal access token of the current user
+//	@Tags			users
+//	@Param			tokenID	path	int	true	"Token ID"
+//	@Success		204		"Token revoked"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/tokens/{tokenID} [delete]
+func (app *application) deletePersonalTokenHandler(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := strconv.ParseInt(chi.URLParam(r, "tokenID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	if err := app.store.PersonalTokens.Delete(r.Context(), user.ID, tokenID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getScopesFromCtx returns nil for requests that were authenticated by JWT
+func getScopesFromCtx(r *http.Request) []string {
+	scopes, _ := r.Context().Value(scopesCtx).([]string)
+	return scopes
+}
+
+func isPersonalTokenRequest(r *http.Request) bool {
+	return r.Context().Value(scopesCtx) != nil
+}
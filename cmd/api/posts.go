@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/O-Nikitin/Social/internal/store"
 	"github.com/go-chi/chi/v5"
@@ -14,23 +15,58 @@ type postKey string
 
 const postCtx postKey = "post"
 
+var (
+	errPublishAtRequired = errors.New("publish_at in the future is required for scheduled post")
+	errPostUnpublish     = errors.New("published post can not become draft or scheduled")
+	errPostNotPublished  = errors.New("post is not published")
+)
+
 // This struct is needed because store.Post contains internal info
 // like "id", "created_at"  and we do not want to let user override it
 type CreatePostPayload struct {
 	Content string   `json:"content" validate:"required,gt=0,max=100"`
 	Title   string   `json:"title" validate:"required,min=1,max=1000"`
 	Tags    []string `json:"tags" validate:"omitempty,dive,min=1,max=20"`
+	// Post is published at once when status is empty and publish_at is not set
+	Status    string     `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt *time.Time `json:"publish_at"`
 }
 
 type UpdatePostPayload struct {
-	Title   *string `json:"title" validate:"omitempty,max=100"`
-	Content *string `json:"content" validate:"omitempty,max=1000"`
+	Title     *string    `json:"title" validate:"omitempty,max=100"`
+	Content   *string    `json:"content" validate:"omitempty,max=1000"`
+	Status    *string    `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// postSchedule checks status and publish time requested by the author.
+// Publish time without status means the post is scheduled
+func postSchedule(status string, publishAt *time.Time) (string, *string, error) {
+	if status == "" {
+		status = store.PostPublished
+		if publishAt != nil {
+			status = store.PostScheduled
+		}
+	}
+
+	switch status {
+	case store.PostScheduled:
+		if publishAt == nil || !publishAt.After(time.Now()) {
+			return "", nil, errPublishAtRequired
+		}
+		at := publishAt.UTC().Format(time.RFC3339)
+		return status, &at, nil
+	default:
+		//Draft has no publish time, published post gets it in the store
+		return status, nil, nil
+	}
 }
 
 // CreatePost godoc
 //
 //	@Summary		Create post
-//	@Description	Create new post
+//	@Description	Create new post. Post is published at once, saved as draft or scheduled for publish_at.
+//	@Description	Draft and scheduled posts are visible only to the author
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -51,18 +87,30 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		app.badRequestResponse(w, r, err)
 		return
 	}
+	status, publishAt, err := postSchedule(post.Status, post.PublishAt)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
 	user := getUserFromCtx(r)
 	DBpost := &store.Post{
-		UserID:  user.ID,
-		Title:   post.Title,
-		Tags:    post.Tags,
-		Content: post.Content,
+		UserID:    user.ID,
+		Title:     post.Title,
+		Tags:      post.Tags,
+		Content:   post.Content,
+		Status:    status,
+		PublishAt: publishAt,
 	}
 
 	if err := app.store.Posts.Create(r.Context(), DBpost); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	//Cached user has posts count, only published posts are counted
+	if DBpost.Status == store.PostPublished {
+		app.invalidateUserCache(r.Context(), DBpost.UserID)
+	}
 
 	if err := app.jsonResponse(w, http.StatusCreated, DBpost); err != nil {
 		app.internalServerError(w, r, err)
@@ -87,6 +135,10 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
 
+	if !app.canSeePost(w, r, post) {
+		return
+	}
+
 	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
@@ -130,6 +182,7 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 		}
 		return
 	}
+	app.invalidateUserCache(r.Context(), getPostFromCtx(r).UserID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -137,7 +190,8 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 // UpdatePost godoc
 //
 //	@Summary		Update post
-//	@Description	Update existing post
+//	@Description	Update existing post. Draft may be scheduled or published, published post can not go back.
+//	@Description	Draft and scheduled posts can be updated only by the author
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -145,6 +199,7 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 //	@Param			body	body		main.UpdatePostPayload	true	"post data"
 //	@Success		200		{object}	main.envelopeSuccess{data=store.Post}
 //	@Failure		400		{object}	main.envelopeErr	"User payload missing"
+//	@Failure		403		{object}	main.envelopeErr
 //	@Failure		404		{object}	main.envelopeErr
 //	@Failure		500		{object}	main.envelopeErr
 //	@Security		ApiKeyAuth
@@ -170,10 +225,38 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		post.Title = *payload.Title
 	}
 
-	if err := app.store.Posts.UpdateByID(r.Context(), post); err != nil {
+	wasPublished := post.Status == store.PostPublished
+	if payload.Status != nil || payload.PublishAt != nil {
+		status := post.Status
+		if payload.Status != nil {
+			status = *payload.Status
+		} else if !wasPublished {
+			//Only publish time is changed, so the post is scheduled
+			status = ""
+		}
+
+		if wasPublished && status != store.PostPublished {
+			app.badRequestResponse(w, r, errPostUnpublish)
+			return
+		}
+
+		if !wasPublished {
+			var err error
+			post.Status, post.PublishAt, err = postSchedule(status, payload.PublishAt)
+			if err != nil {
+				app.badRequestResponse(w, r, err)
+				return
+			}
+		}
+	}
+
+	if err := app.store.Posts.UpdateByID(r.Context(), post, getUserFromCtx(r).ID); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	if !wasPublished && post.Status == store.PostPublished {
+		app.invalidateUserCache(r.Context(), post.UserID)
+	}
 
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
@@ -181,6 +264,46 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// GetDrafts godoc
+//
+//	@Summary		Draft and scheduled posts
+//	@Description	Unpublished posts of the current user, scheduled first by publish time
+//	@Tags			posts
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	main.envelopeSuccess{data=[]store.Post}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/posts/drafts [get]
+func (app *application) getDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	pq := store.PaginatedQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	posts, err := app.store.Posts.GetDrafts(r.Context(), getUserFromCtx(r).ID, pq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
 func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		paramID := chi.URLParam(r, "postID")
@@ -206,6 +329,33 @@ func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// canSeePost is canSeePosts for one post. Draft and scheduled posts are
+// visible only to the author
+func (app *application) canSeePost(w http.ResponseWriter, r *http.Request, post *store.Post) bool {
+	if post.Status != store.PostPublished && post.UserID != getUserFromCtx(r).ID {
+		app.notFoundResponse(w, r, store.ErrNotFound)
+		return false
+	}
+
+	return app.canSeePosts(w, r, post.UserID)
+}
+
+// canSeePosts writes not found response if the current user can not see posts
+// of private author, so existence of the post is not revealed
+func (app *application) canSeePosts(w http.ResponseWriter, r *http.Request, authorID int64) bool {
+	allowed, err := app.store.Followers.CanSeePosts(r.Context(), getUserFromCtx(r).ID, authorID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return false
+	}
+	if !allowed {
+		app.notFoundResponse(w, r, store.ErrNotFound)
+		return false
+	}
+
+	return true
+}
+
 func getPostFromCtx(r *http.Request) *store.Post {
 	post, _ := r.Context().Value(postCtx).(*store.Post)
 	return post
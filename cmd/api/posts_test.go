@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/golang/mock/gomock"
+)
+
+func TestPosts_Drafts(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe"}
+	moderator := &store.User{ID: 3, Username: "moderator", Role: store.Role{ID: 2, Name: store.ModeratorRole, Level: 2}}
+	draft := &store.Post{ID: 5, UserID: 2, Status: store.PostDraft}
+
+	newRequest := authRequests(app, mocks, user)
+	asModerator := authRequests(app, mocks, moderator)
+
+	t.Run("Should_require_future_publish_time",
+		func(t *testing.T) {
+			past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+			req := newRequest(t, http.MethodPost, "/v1/posts",
+				`{"title":"t","content":"c","status":"scheduled","publish_at":"`+past+`"}`)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_schedule_post",
+		func(t *testing.T) {
+			at := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+			req := newRequest(t, http.MethodPost, "/v1/posts",
+				`{"title":"t","content":"c","publish_at":"`+at.Format(time.RFC3339)+`"}`)
+			mocks.Posts.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, post *store.Post) error {
+					if post.Status != store.PostScheduled || post.PublishAt == nil ||
+						*post.PublishAt != at.Format(time.RFC3339) {
+						t.Errorf("unexpected schedule %q %v", post.Status, post.PublishAt)
+					}
+					return nil
+				})
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusCreated, t)
+		})
+
+	t.Run("Should_hide_draft_of_other_user",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/posts/5", "")
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(5)).Return(draft, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_not_allow_to_comment_draft",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/posts/5/comments", `{"content":"hi"}`)
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(5)).Return(draft, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_not_allow_moderator_to_publish_draft_of_other_user",
+		func(t *testing.T) {
+			req := asModerator(t, http.MethodPatch, "/v1/posts/5", `{"status":"published"}`)
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(5)).Return(draft, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_list_own_drafts",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/posts/drafts?limit=5", "")
+			mocks.Posts.EXPECT().GetDrafts(gomock.Any(), int64(1), store.PaginatedQuery{Limit: 5}).
+				Return([]store.Post{{ID: 6, UserID: 1, Status: store.PostDraft}}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+		})
+
+	t.Run("Should_publish_due_posts_and_reset_authors_cache",
+		func(t *testing.T) {
+			app.config.redis.enabled = true
+			defer func() { app.config.redis.enabled = false }()
+
+			mocks.Posts.EXPECT().PublishDue(gomock.Any(), publishBatch).Return([]int64{2, 2}, nil)
+			//Cache of the author is reset once for both posts
+			mocks.Cache.EXPECT().Delete(gomock.Any(), int64(2)).Return(nil).Times(1)
+
+			app.publishDuePosts(context.Background())
+		})
+}
+
+func TestPosts_Revisions(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	moderatorRole := &store.Role{ID: 2, Name: "moderator", Level: 2,
+		Permissions: []string{store.PermPostsUpdateAny}}
+	user := &store.User{ID: 1, Username: "john_doe", Role: *moderatorRole}
+
+	newRequest := authRequests(app, mocks, user)
+	ownPost := func() *store.Post {
+		return &store.Post{ID: 5, UserID: 1, Title: "t", Content: "one\ntwo", Status: store.PostPublished, Version: 2}
+	}
+
+	t.Run("Should_validate_version",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/posts/5/revisions/abc", "")
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(5)).Return(ownPost(), nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_diff_with_current_version",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/posts/5/revisions/diff?from=0", "")
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(5)).Return(ownPost(), nil)
+			mocks.Revisions.EXPECT().GetByVersion(gomock.Any(), int64(5), 0).
+				Return(&store.PostRevision{Version: 0, Title: "t", Content: "one"}, nil)
+			mocks.Revisions.EXPECT().GetByVersion(gomock.Any(), int64(5), 2).
+				Return(&store.PostRevision{Version: 2, Title: "t", Content: "one\ntwo"}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+			var response struct {
+				Data RevisionDiff `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Data.Content) != 2 || response.Data.Content[1].Op != "insert" {
+				t.Errorf("unexpected diff %v", response.Data.Content)
+			}
+		})
+
+	t.Run("Should_not_show_history_without_permission",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/posts/6/revisions", "")
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(6)).Return(&store.Post{ID: 6, UserID: 2, Status: store.PostPublished}, nil)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(2)).Return(&store.Role{ID: 2, Name: "moderator"}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_hide_history_of_draft_from_moderator",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/posts/6/revisions/diff?from=1", "")
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(6)).Return(&store.Post{ID: 6, UserID: 2, Status: store.PostDraft}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_not_restore_scheduled_post_of_other_user",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/posts/6/revisions/1/restore", "")
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(6)).Return(&store.Post{ID: 6, UserID: 2, Status: store.PostScheduled}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_restore_post_of_other_user_as_moderator",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/posts/6/revisions/1/restore", "")
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(6)).
+				Return(&store.Post{ID: 6, UserID: 2, Title: "spam", Status: store.PostPublished, Version: 3}, nil)
+			mocks.Roles.EXPECT().GetByID(gomock.Any(), int64(2)).Return(moderatorRole, nil)
+			mocks.Revisions.EXPECT().GetByVersion(gomock.Any(), int64(6), 1).
+				Return(&store.PostRevision{Version: 1, Title: "hello", Content: "world"}, nil)
+			mocks.Posts.EXPECT().UpdateByID(gomock.Any(), gomock.Any(), int64(1)).DoAndReturn(
+				func(_ context.Context, post *store.Post, _ int64) error {
+					if post.Title != "hello" || post.Content != "world" || post.Version != 3 {
+						t.Errorf("unexpected restored post %+v", post)
+					}
+					post.Version++
+					return nil
+				})
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+		})
+}
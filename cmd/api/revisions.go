@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/O-Nikitin/Social/internal/diff"
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var errPostChanged = errors.New("post was changed, reload it and try again")
+
+// RevisionDiff shows how title and content changed between two versions
+type RevisionDiff struct {
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Title   []diff.Line `json:"title"`
+	Content []diff.Line `json:"content"`
+}
+
+// GetRevisions godoc
+//
+//	@Summary		Post revisions
+//	@Description	Saved versions of the post, the newest first. Available to the author and users with
+//	@Description	posts.update.any permission, history of draft and scheduled posts only to the author
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	main.envelopeSuccess{data=[]store.PostRevision}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/revisions [get]
+func (app *application) getRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	pq := store.PaginatedQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	revisions, err := app.store.PostRevisions.GetByPostID(r.Context(), getPostFromCtx(r).ID, pq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, revisions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetRevision godoc
+//
+//	@Summary		Post revision
+//	@Description	One saved version of the post
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Param			version	path		int	true	"Version"
+//	@Success		200		{object}	main.envelopeSuccess{data=store.PostRevision}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/revisions/{version} [get]
+func (app *application) getRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	revision, ok := app.revision(w, r, chi.URLParam(r, "version"))
+	if !ok {
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, revision); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetRevisionDiff godoc
+//
+//	@Summary		Diff of post revisions
+//	@Description	Line diff of title and content between two versions, "to" is the current version by default
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Param			from	query		int	true	"Old version"
+//	@Param			to		query		int	false	"New version"
+//	@Success		200		{object}	main.envelopeSuccess{data=main.RevisionDiff}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/revisions/diff [get]
+func (app *application) getRevisionDiffHandler(w http.ResponseWriter, r *http.Request) {
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = strconv.Itoa(getPostFromCtx(r).Version)
+	}
+
+	from, ok := app.revision(w, r, r.URL.Query().Get("from"))
+	if !ok {
+		return
+	}
+	target, ok := app.revision(w, r, to)
+	if !ok {
+		return
+	}
+
+	result := RevisionDiff{
+		From:    from.Version,
+		To:      target.Version,
+		Title:   diff.Lines(from.Title, target.Title),
+		Content: diff.Lines(from.Content, target.Content),
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, result); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RestoreRevision godoc
+//
+//	@Summary		Restore post revision
+//	@Description	Title and content of the version become the new version of the post, history is kept
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Param			version	path		int	true	"Version"
+//	@Success		200		{object}	main.envelopeSuccess{data=store.Post}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		409		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/revisions/{version}/restore [post]
+func (app *application) restoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	revision, ok := app.revision(w, r, chi.URLParam(r, "version"))
+	if !ok {
+		return
+	}
+
+	post := getPostFromCtx(r)
+	post.Title = revision.Title
+	post.Content = revision.Content
+
+	if err := app.store.Posts.UpdateByID(r.Context(), post, getUserFromCtx(r).ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			//Version of the post was changed by another request
+			app.conflictResponse(w, r, errPostChanged)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// revision loads the version of the post from the context. It writes error
+// response and returns false if the version is invalid or not found
+func (app *application) revision(w http.ResponseWriter, r *http.Request, param string) (*store.PostRevision, bool) {
+	version, err := strconv.Atoi(param)
+	if err != nil || version < 0 {
+		app.badRequestResponse(w, r, errors.New("invalid version"))
+		return nil, false
+	}
+
+	revision, err := app.store.PostRevisions.GetByVersion(r.Context(), getPostFromCtx(r).ID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return revision, true
+}
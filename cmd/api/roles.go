@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type CreateRolePayload struct {
+	Name        string   `json:"name" validate:"required,max=50,alphanum,lowercase"`
+	Level       int      `json:"level" validate:"required,gte=1"`
+	Description string   `json:"description" validate:"max=500"`
+	Permissions []string `json:"permissions" validate:"dive,permission"`
+}
+
+type SetPermissionsPayload struct {
+	Permissions []string `json:"permissions" validate:"dive,permission"`
+}
+
+type AssignRolePayload struct {
+	Role string `json:"role" validate:"required,max=50"`
+}
+
+// GetRoles godoc
+//
+//	@Summary		List roles
+//	@Description	All roles ordered by level. Higher level has permissions of all lower levels
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	main.envelopeSuccess{data=[]store.Role}
+//	@Failure		403	{object}	main.envelopeErr
+//	@Failure		500	{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles [get]
+func (app *application) getRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := app.store.Roles.GetAll(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, roles); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// CreateRole godoc
+//
+//	@Summary		Create a role
+//	@Description	Creates custom role. Level can not be higher than the level of the current user
+//	@Description	and only permissions of the current user can be given
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateRolePayload	true	"Role"
+//	@Success		201		{object}	main.envelopeSuccess{data=store.Role}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		409		{object}	main.envelopeErr	"Role name is taken"
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles [post]
+func (app *application) createRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateRolePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	currentUser := getUserFromCtx(r)
+	if payload.Level > currentUser.Role.Level {
+		app.forbiddenRepsonse(w, r)
+		return
+	}
+	allowed, err := app.canGrant(r, payload.Permissions)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if !allowed {
+		app.forbiddenRepsonse(w, r)
+		return
+	}
+
+	role := &store.Role{
+		Name:        payload.Name,
+		Level:       payload.Level,
+		Description: payload.Description,
+		Permissions: payload.Permissions,
+	}
+	if err := app.store.Roles.Create(r.Context(), role, currentUser.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, role); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// SetRolePermissions godoc
+//
+//	@Summary		Set permissions of a role
+//	@Description	Replaces permissions of the role. Roles with the same or higher level than the current user
+//	@Description	can not be changed and only permissions of the current user can be given
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			roleName	path		string					true	"Role name"
+//	@Param			payload		body		SetPermissionsPayload	true	"Permissions"
+//	@Success		200			{object}	main.envelopeSuccess{data=store.Role}
+//	@Failure		400			{object}	main.envelopeErr
+//	@Failure		403			{object}	main.envelopeErr
+//	@Failure		404			{object}	main.envelopeErr
+//	@Failure		500			{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles/{roleName}/permissions [put]
+func (app *application) setRolePermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload SetPermissionsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	role, err := app.store.Roles.GetByName(r.Context(), chi.URLParam(r, "roleName"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	currentUser := getUserFromCtx(r)
+	if role.Level >= currentUser.Role.Level {
+		app.forbiddenRepsonse(w, r)
+		return
+	}
+	allowed, err := app.canGrant(r, payload.Permissions)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if !allowed {
+		app.forbiddenRepsonse(w, r)
+		return
+	}
+
+	role.Permissions = payload.Permissions
+	if err := app.store.Roles.SetPermissions(r.Context(), role, currentUser.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.invalidateRoleCache(r.Context(), role.ID)
+
+	if err := app.jsonResponse(w, http.StatusOK, role); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// AssignRole godoc
+//
+//	@Summary		Assign role to a user
+//	@Description	Changes the role of the user. Admin can not change roles of users with the same or higher level
+//	@Description	and can not give a role higher than own. The change is recorded in the audit log
+//	@Tags			admin
+//	@Accept			json
+//	@Param			userID	path	int					true	"userID"
+//	@Param			payload	body	AssignRolePayload	true	"Role name"
+//	@Success		204		"Role assigned"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr	"User or role not found"
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/role [put]
+func (app *application) assignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := getUserFromCtx(r)
+	userID, err := targetUserID(r, currentUser)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload AssignRolePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	role, err := app.store.Roles.GetByName(r.Context(), payload.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	user, err := app.store.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if role.Level > currentUser.Role.Level || user.Role.Level >= currentUser.Role.Level {
+		app.forbiddenRepsonse(w, r)
+		return
+	}
+
+	if err := app.store.Roles.Assign(r.Context(), userID, role.Name, currentUser.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	//Role is checked from cached user
+	app.invalidateUserCache(r.Context(), userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAuditLog godoc
+//
+//	@Summary		Audit log
+//	@Description	Admin actions, the newest first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	main.envelopeSuccess{data=[]store.AuditEntry}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/admin/audit [get]
+func (app *application) getAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	pq := store.PaginatedQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entries, err := app.store.Audit.GetAll(r.Context(), pq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, entries); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// canGrant reports if the current user has all the permissions,
+// so nobody can give more rights than they have
+func (app *application) canGrant(r *http.Request, permissions []string) (bool, error) {
+	role, err := app.getRole(r.Context(), getUserFromCtx(r).Role.ID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, permission := range permissions {
+		if !slices.Contains(role.Permissions, permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Scheduled posts published in one pass of the scheduler
+const publishBatch = 100
+
+// runScheduler periodically publishes scheduled posts whose time has come.
+// Schedule is stored in the posts table, so it survives restarts and posts
+// missed while the api was down are published on the first pass.
+// It returns when ctx is cancelled
+func (app *application) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(app.config.scheduler.interval)
+	defer ticker.Stop()
+
+	for {
+		app.publishDuePosts(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) publishDuePosts(ctx context.Context) {
+	for ctx.Err() == nil {
+		authors, err := app.store.Posts.PublishDue(ctx, publishBatch)
+		if err != nil {
+			app.logger.Errorw("error publishing scheduled posts", "err", err.Error())
+			return
+		}
+		if len(authors) == 0 {
+			return
+		}
+		app.logger.Infow("Scheduled posts published", "count", len(authors))
+
+		//Cached user has posts count
+		seen := make(map[int64]bool)
+		for _, id := range authors {
+			if !seen[id] {
+				seen[id] = true
+				app.invalidateUserCache(ctx, id)
+			}
+		}
+
+		if len(authors) < publishBatch {
+			return
+		}
+	}
+}
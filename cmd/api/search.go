@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/O-Nikitin/Social/internal/store"
+)
+
+// SearchUsers godoc
+//
+//	@Summary		Search users
+//	@Description	Search users by username and display name, exact matches go first. Similar names are found too.
+//	@Description	Autocomplete mode(for @-mentions) matches only by prefix and ignores offset
+//	@Tags			users
+//	@Produce		json
+//	@Param			q				query		string	true	"Search text"
+//	@Param			autocomplete	query		bool	false	"Prefix match only"
+//	@Param			limit			query		int		false	"Limit"
+//	@Param			offset			query		int		false	"Offset"
+//	@Success		200				{object}	main.envelopeSuccess{data=[]store.UserSummary}
+//	@Failure		400				{object}	main.envelopeErr
+//	@Failure		500				{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/search [get]
+func (app *application) searchUsersHandler(w http.ResponseWriter, r *http.Request) {
+	sq := store.UserSearchQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+	sq, err := sq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(sq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	users, err := app.store.Users.Search(r.Context(), getUserFromCtx(r).ID, sq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, users); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
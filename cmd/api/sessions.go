@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const maxUserAgentLen = 255
+
+type sessionKey string
+
+const sessionCtx sessionKey = "session"
+
+var errSessionRevoked = errors.New("session was revoked")
+
+type UserSession struct {
+	store.Session
+	// Current is true for the session which made the request
+	Current bool `json:"current"`
+}
+
+// GetSessions godoc
+//
+//	@Summary		List sessions
+//	@Description	Lists devices where the current user is logged in
+//	@Tags			users
+//	@Produce		json
+//	@Success		200	{object}	main.envelopeSuccess{data=[]main.UserSession}
+//	@Failure		401	{object}	main.envelopeErr
+//	@Failure		403	{object}	main.envelopeErr
+//	@Failure		500	{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/sessions [get]
+func (app *application) getSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	sessions, err := app.store.Sessions.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	currentID := getSessionIDFromCtx(r)
+	userSessions := make([]UserSession, 0, len(sessions))
+	for _, session := range sessions {
+		userSessions = append(userSessions, UserSession{
+			Session: session,
+			Current: session.ID == currentID,
+		})
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, userSessions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteSession godoc
+//
+//	@Summary		Revoke session
+//	@Description	Logs out one session of the current user
+//	@Tags			users
+//	@Param			sessionID	path	string	true	"Session ID"
+//	@Success		204			"Session revoked"
+//	@Failure		400			{object}	main.envelopeErr
+//	@Failure		401			{object}	main.envelopeErr
+//	@Failure		404			{object}	main.envelopeErr
+//	@Failure		500			{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/sessions/{sessionID} [delete]
+func (app *application) deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	if err := uuid.Validate(sessionID); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	if err := app.store.Sessions.Delete(r.Context(), user.ID, sessionID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.invalidateSessionsCache(r.Context(), user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteOtherSessions godoc
+//
+//	@Summary		Revoke other sessions
+//	@Description	Logs out every session of the current user except the one which made the request
+//	@Tags			users
+//	@Success		204	"Sessions revoked"
+//	@Failure		401	{object}	main.envelopeErr
+//	@Failure		500	{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/sessions [delete]
+func (app *application) deleteOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	err := app.store.Sessions.DeleteOthers(r.Context(), user.ID, getSessionIDFromCtx(r))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.invalidateSessionsCache(r.Context(), user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionFromRequest fills the device info of the session
+func sessionFromRequest(r *http.Request, userID int64) *store.Session {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLen {
+		userAgent = userAgent[:maxUserAgentLen]
+	}
+
+	return &store.Session{
+		UserID:    userID,
+		UserAgent: userAgent,
+		IP:        clientIP(r),
+	}
+}
+
+// checkSession returns errSessionRevoked if the session is not active anymore
+func (app *application) checkSession(ctx context.Context, userID int64, sessionID string) error {
+	sessionIDs, err := app.activeSessionIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(sessionIDs, sessionID) {
+		return errSessionRevoked
+	}
+
+	return nil
+}
+
+// activeSessionIDs is called for every request, so IDs are taken from cache if possible
+func (app *application) activeSessionIDs(ctx context.Context, userID int64) ([]string, error) {
+	if app.config.redis.enabled {
+		sessionIDs, err := app.cacheStorage.Sessions.Get(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if sessionIDs != nil {
+			return sessionIDs, nil
+		}
+	}
+
+	sessions, err := app.store.Sessions.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+
+	if app.config.redis.enabled {
+		if err := app.cacheStorage.Sessions.Set(ctx, userID, sessionIDs); err != nil {
+			app.logger.Warnw("Sessions were not updated in cache", "user_id", userID, "err", err.Error())
+		}
+	}
+
+	return sessionIDs, nil
+}
+
+// getSessionIDFromCtx returns empty string for personal access tokens
+// and tokens issued before sessions were added
+func getSessionIDFromCtx(r *http.Request) string {
+	sessionID, _ := r.Context().Value(sessionCtx).(string)
+	return sessionID
+}
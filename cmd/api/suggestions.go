@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/O-Nikitin/Social/internal/store"
+)
+
+// Suggestions are computed and cached once per user, pages are cut from them
+const maxSuggestions = 50
+
+// GetSuggestions godoc
+//
+//	@Summary		Who to follow
+//	@Description	Users followed by the users whom the current user follows. More mutual connections and recent posts rank higher.
+//	@Description	Popular accounts are suggested to users who do not follow anybody yet
+//	@Tags			users
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	main.envelopeSuccess{data=[]store.Suggestion}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/suggestions [get]
+func (app *application) getSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	pq := store.PaginatedQuery{
+		Limit:  10,
+		Offset: 0,
+	}
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	suggestions, err := app.suggestions(r.Context(), getUserFromCtx(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	start := min(pq.Offset, len(suggestions))
+	end := min(start+pq.Limit, len(suggestions))
+	if err := app.jsonResponse(w, http.StatusOK, suggestions[start:end]); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// suggestions returns users to follow from cache if it is enabled, or from DB
+func (app *application) suggestions(ctx context.Context, userID int64) ([]store.Suggestion, error) {
+	if app.config.redis.enabled {
+		suggestions, err := app.cacheStorage.Suggestions.Get(ctx, userID)
+		if err != nil {
+			app.logger.Warnw("Suggestions were not read from cache", "user_id", userID, "err", err.Error())
+		} else if suggestions != nil {
+			return suggestions, nil
+		}
+	}
+
+	suggestions, err := app.store.Followers.GetSuggestions(ctx, userID, maxSuggestions)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.config.redis.enabled {
+		if err := app.cacheStorage.Suggestions.Set(ctx, userID, suggestions); err != nil {
+			app.logger.Warnw("Suggestions were not updated in cache", "user_id", userID, "err", err.Error())
+		}
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var errInvalidDuration = errors.New("duration must be positive, e.g. 72h")
+
+type SuspendUserPayload struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+	// Go duration like "72h". Empty duration suspends permanently
+	Duration string `json:"duration" validate:"max=20"`
+}
+
+// SuspendUser godoc
+//
+//	@Summary		Suspend a user
+//	@Description	Suspends the user for the duration or permanently if duration is empty. Active suspension is replaced.
+//	@Description	Sessions of the user are revoked, tokens stop working and content is hidden. Admins can not be suspended
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int					true	"userID"
+//	@Param			payload	body		SuspendUserPayload	true	"Reason and duration"
+//	@Success		201		{object}	main.envelopeSuccess{data=store.Suspension}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/suspend [put]
+func (app *application) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := getUserFromCtx(r)
+	userID, err := targetUserID(r, currentUser)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload SuspendUserPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	suspension := &store.Suspension{
+		UserID:  userID,
+		AdminID: &currentUser.ID,
+		Reason:  payload.Reason,
+	}
+	if payload.Duration != "" {
+		duration, err := time.ParseDuration(payload.Duration)
+		if err != nil || duration <= 0 {
+			app.badRequestResponse(w, r, errInvalidDuration)
+			return
+		}
+		expiresAt := time.Now().Add(duration).UTC().Format(time.RFC3339)
+		suspension.ExpiresAt = &expiresAt
+	}
+
+	user, err := app.store.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if user.Role.Level >= currentUser.Role.Level {
+		app.forbiddenRepsonse(w, r)
+		return
+	}
+
+	if err := app.store.Suspensions.Suspend(r.Context(), suspension); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	//Cached user would let the tokens work until the cache expires
+	app.invalidateUserCache(r.Context(), userID)
+	app.invalidateSessionsCache(r.Context(), userID)
+
+	if err := app.jsonResponse(w, http.StatusCreated, suspension); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UnsuspendUser godoc
+//
+//	@Summary		Lift suspension
+//	@Description	Ends active suspension of the user before it expires. Revoked sessions are not restored
+//	@Tags			admin
+//	@Param			userID	path	int	true	"userID"
+//	@Success		204		"Suspension lifted"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr	"User is not suspended"
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/unsuspend [put]
+func (app *application) unsuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := getUserFromCtx(r)
+	userID, err := targetUserID(r, currentUser)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Suspensions.Lift(r.Context(), userID, currentUser.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.invalidateUserCache(r.Context(), userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSuspensions godoc
+//
+//	@Summary		Suspension history
+//	@Description	All suspensions of the user, the newest first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userID	path		int	true	"userID"
+//	@Success		200		{object}	main.envelopeSuccess{data=[]store.Suspension}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		403		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/suspensions [get]
+func (app *application) getSuspensionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	suspensions, err := app.store.Suspensions.GetHistory(r.Context(), userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, suspensions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -11,6 +12,7 @@ import (
 	mock_storage "github.com/O-Nikitin/Social/cmd/api/mock/store"
 	"github.com/O-Nikitin/Social/internal/store"
 	"github.com/O-Nikitin/Social/internal/store/cache"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	"go.uber.org/zap"
 )
@@ -21,10 +23,24 @@ type AppMocks struct {
 	Comments  *mock_storage.MockComments
 	Followers *mock_storage.MockFollowers
 	Roles     *mock_storage.MockRoles
+	Refresh   *mock_storage.MockRefreshTokens
+	Sessions  *mock_storage.MockSessions
+	TwoFactor *mock_storage.MockTwoFactor
+	Tokens    *mock_storage.MockPersonalTokens
+	Ident     *mock_storage.MockIdentities
+	Blocks    *mock_storage.MockBlocks
+	Exports   *mock_storage.MockDataExports
+	Suspend   *mock_storage.MockSuspensions
+	Audit     *mock_storage.MockAudit
+	Revisions *mock_storage.MockPostRevisions
 	Cache     *mock_storage.MockUserCache
+	SessCache *mock_storage.MockSessionCache
+	SuggCache *mock_storage.MockSuggestionCache
+	RoleCache *mock_storage.MockRoleCache
 	Mailer    *mock_mailer.MockClient
 	Auth      *mock_auth.MockAuthenticator
 	Limiter   *mock_limiter.MockLimiter
+	Login     *mock_limiter.MockLoginThrottler
 }
 
 func newTestApp(t *testing.T, cfg config) (*application, *AppMocks) {
@@ -39,35 +55,63 @@ func newTestApp(t *testing.T, cfg config) (*application, *AppMocks) {
 	mockComments := mock_storage.NewMockComments(ctrl)
 	mockFollowers := mock_storage.NewMockFollowers(ctrl)
 	mockRoles := mock_storage.NewMockRoles(ctrl)
+	mockRefresh := mock_storage.NewMockRefreshTokens(ctrl)
+	mockSessions := mock_storage.NewMockSessions(ctrl)
+	mockTwoFactor := mock_storage.NewMockTwoFactor(ctrl)
+	mockTokens := mock_storage.NewMockPersonalTokens(ctrl)
+	mockIdentities := mock_storage.NewMockIdentities(ctrl)
+	mockBlocks := mock_storage.NewMockBlocks(ctrl)
+	mockExports := mock_storage.NewMockDataExports(ctrl)
+	mockSuspensions := mock_storage.NewMockSuspensions(ctrl)
+	mockAudit := mock_storage.NewMockAudit(ctrl)
+	mockRevisions := mock_storage.NewMockPostRevisions(ctrl)
 
 	mockUserCache := mock_storage.NewMockUserCache(ctrl)
+	mockSessionCache := mock_storage.NewMockSessionCache(ctrl)
+	mockSuggestionCache := mock_storage.NewMockSuggestionCache(ctrl)
+	mockRoleCache := mock_storage.NewMockRoleCache(ctrl)
 
 	mockMailer := mock_mailer.NewMockClient(ctrl)
 
 	mockAuth := mock_auth.NewMockAuthenticator(ctrl)
 
 	mockLimiter := mock_limiter.NewMockLimiter(ctrl)
+	mockLogin := mock_limiter.NewMockLoginThrottler(ctrl)
 
 	storage := store.Storage{
-		Posts:     mockPosts,
-		Users:     mockUsers,
-		Comments:  mockComments,
-		Followers: mockFollowers,
-		Roles:     mockRoles,
+		Posts:          mockPosts,
+		Users:          mockUsers,
+		Comments:       mockComments,
+		Followers:      mockFollowers,
+		Roles:          mockRoles,
+		RefreshTokens:  mockRefresh,
+		Sessions:       mockSessions,
+		TwoFactor:      mockTwoFactor,
+		PersonalTokens: mockTokens,
+		Identities:     mockIdentities,
+		Blocks:         mockBlocks,
+		DataExports:    mockExports,
+		Suspensions:    mockSuspensions,
+		Audit:          mockAudit,
+		PostRevisions:  mockRevisions,
 	}
 
 	cache := cache.Storage{
-		Users: mockUserCache,
+		Users:       mockUserCache,
+		Sessions:    mockSessionCache,
+		Suggestions: mockSuggestionCache,
+		Roles:       mockRoleCache,
 	}
 
 	a := &application{
-		logger:        log,
-		store:         storage,
-		cacheStorage:  cache,
-		mailer:        mockMailer,
-		authenticator: mockAuth,
-		config:        cfg,
-		rateLimiter:   mockLimiter,
+		logger:         log,
+		store:          storage,
+		cacheStorage:   cache,
+		mailer:         mockMailer,
+		authenticator:  mockAuth,
+		config:         cfg,
+		rateLimiter:    mockLimiter,
+		loginThrottler: mockLogin,
 	}
 	m := &AppMocks{
 		Posts:     mockPosts,
@@ -75,15 +119,56 @@ func newTestApp(t *testing.T, cfg config) (*application, *AppMocks) {
 		Comments:  mockComments,
 		Followers: mockFollowers,
 		Roles:     mockRoles,
+		Refresh:   mockRefresh,
+		Sessions:  mockSessions,
+		TwoFactor: mockTwoFactor,
+		Tokens:    mockTokens,
+		Ident:     mockIdentities,
+		Blocks:    mockBlocks,
+		Exports:   mockExports,
+		Suspend:   mockSuspensions,
+		Audit:     mockAudit,
+		Revisions: mockRevisions,
 		Cache:     mockUserCache,
+		SessCache: mockSessionCache,
+		SuggCache: mockSuggestionCache,
+		RoleCache: mockRoleCache,
 		Mailer:    mockMailer,
 		Auth:      mockAuth,
 		Limiter:   mockLimiter,
+		Login:     mockLogin,
 	}
 
 	return a, m
 }
 
+const testToken = "abc123"
+
+// authRequests returns factory of requests made by the user with a valid
+// access token. The user is read from cache when redis is enabled
+func authRequests(app *application, mocks *AppMocks, user *store.User) func(t *testing.T, method, url, body string) *http.Request {
+	return func(t *testing.T, method, url, body string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("Request not created: ", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		mocks.Auth.EXPECT().ValidateToken(testToken).Return(&jwt.Token{
+			Valid:  true,
+			Claims: jwt.MapClaims{"sub": float64(user.ID)}, // jwt.MapClaims decodes numbers as float64
+		}, nil)
+		if app.config.redis.enabled {
+			mocks.Cache.EXPECT().Get(gomock.Any(), user.ID).Return(user, nil)
+		} else {
+			mocks.Users.EXPECT().GetByID(gomock.Any(), user.ID).Return(user, nil)
+		}
+
+		return req
+	}
+}
+
 func executeRequest(req *http.Request, mux http.Handler) *httptest.ResponseRecorder {
 	rr := httptest.NewRecorder()
 	mux.ServeHTTP(rr, req)
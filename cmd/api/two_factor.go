@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/auth"
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const recoveryCodesCount = 10
+
+var errInvalidTwoFactorCode = errors.New("invalid two-factor code")
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type ConfirmTwoFactorPayload struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// DisableTwoFactorPayload has current password, it is not required for users without password
+type DisableTwoFactorPayload struct {
+	CurrentPassword string `json:"current_password" validate:"max=72"`
+}
+
+type TwoFactorChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type TwoFactorLoginPayload struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code,omitempty,max=20"`
+}
+
+// EnrollTwoFactor godoc
+//
+//	@Summary		Start 2FA enrollment
+//	@Description	Generates a TOTP secret. 2FA is enabled only after the first code is confirmed
+//	@Tags			users
+//	@Produce		json
+//	@Success		201	{object}	main.envelopeSuccess{data=main.TwoFactorEnrollment}
+//	@Failure		409	{object}	main.envelopeErr	"2FA already enabled"
+//	@Failure		500	{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/2fa [post]
+func (app *application) enrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.TwoFactor.Enroll(r.Context(), user.ID, secret); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	enrollment := TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(app.config.auth.token.iss, user.Email, secret),
+	}
+	if err := app.jsonResponse(w, http.StatusCreated, enrollment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ConfirmTwoFactor godoc
+//
+//	@Summary		Confirm 2FA enrollment
+//	@Description	Enables 2FA if the code from authenticator app is valid. Returns recovery codes, they are shown only once
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ConfirmTwoFactorPayload	true	"Code from authenticator app"
+//	@Success		200		{object}	main.envelopeSuccess{data=main.RecoveryCodes}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		409		{object}	main.envelopeErr	"2FA already enabled"
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/2fa/confirm [post]
+func (app *application) confirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ConfirmTwoFactorPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	totp, err := app.store.TwoFactor.Get(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if totp.Confirmed {
+		app.conflictResponse(w, r, store.ErrConflict)
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(totp.Secret, payload.Code, time.Now())
+	if !ok {
+		app.badRequestResponse(w, r, errInvalidTwoFactorCode)
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.TwoFactor.Confirm(r.Context(), user.ID, step, codes); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, RecoveryCodes{RecoveryCodes: codes}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DisableTwoFactor godoc
+//
+//	@Summary		Disable 2FA
+//	@Description	Disables 2FA and removes recovery codes. User without password(created by OIDC login)
+//	@Description	confirms it with the session created less than 10 minutes ago
+//	@Tags			users
+//	@Accept			json
+//	@Param			payload	body	DisableTwoFactorPayload	true	"Current password"
+//	@Success		204		"2FA disabled"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		401		{object}	main.envelopeErr	"Login is too old to disable 2FA without password"
+//	@Failure		404		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/2fa [delete]
+func (app *application) disableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var payload DisableTwoFactorPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.store.Users.GetByID(r.Context(), getUserFromCtx(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !app.confirmUser(w, r, user, payload.CurrentPassword) {
+		return
+	}
+
+	if err := app.store.TwoFactor.Disable(r.Context(), user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createTwoFactorTokenHandler godoc
+//
+//	@Summary		Second login step
+//	@Description	Exchanges mfa_token from /authentication/token and TOTP or recovery code for access and refresh tokens
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		TwoFactorLoginPayload	true	"MFA token and code"
+//	@Success		201		{object}	main.envelopeSuccess{data=main.TokenPair}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		401		{object}	main.envelopeErr
+//	@Failure		423		{object}	main.envelopeErr	"Too many failed attempts, see Retry-After header"
+//	@Failure		500		{object}	main.envelopeErr
+//	@Router			/authentication/token/2fa [post]
+func (app *application) createTwoFactorTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload TwoFactorLoginPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	jwtToken, err := app.authenticator.ValidateToken(payload.MFAToken)
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	claims, _ := jwtToken.Claims.(jwt.MapClaims)
+	if claims["typ"] != mfaTokenType {
+		app.unauthorizedErrorResponse(w, r, fmt.Errorf("unexpected token type %v", claims["typ"]))
+		return
+	}
+
+	userID, err := userIDFromClaims(claims)
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	//Codes are short, so they are throttled together with passwords of the account
+	user, err := app.store.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	accountKey, ipKey := loginThrottleKeys(r, user.Email)
+	if lockedFor := app.loginLockedFor(r.Context(), accountKey, ipKey); lockedFor > 0 {
+		app.accountLockedResponse(w, r, lockedFor)
+		return
+	}
+
+	err = app.verifySecondFactor(r.Context(), userID, payload.Code, payload.RecoveryCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, errInvalidTwoFactorCode):
+			app.failedLoginResponse(w, r, user.Email, user, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.loginSucceeded(r, user.Email)
+
+	tokens, err := app.startSession(r, userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// verifySecondFactor accepts either TOTP code or one of the recovery codes.
+// Every code can be used only once
+func (app *application) verifySecondFactor(ctx context.Context, userID int64, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		err := app.store.TwoFactor.UseRecoveryCode(ctx, userID, recoveryCode)
+		if errors.Is(err, store.ErrNotFound) {
+			return errInvalidTwoFactorCode
+		}
+		return err
+	}
+
+	totp, err := app.store.TwoFactor.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return errInvalidTwoFactorCode
+		}
+		return err
+	}
+	if !totp.Confirmed {
+		return errInvalidTwoFactorCode
+	}
+
+	step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return errInvalidTwoFactorCode
+	}
+
+	//Code that was already used could be seen by someone else
+	err = app.store.TwoFactor.UseCode(ctx, userID, step)
+	if errors.Is(err, store.ErrConflict) {
+		return errInvalidTwoFactorCode
+	}
+	return err
+}
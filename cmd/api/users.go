@@ -2,26 +2,86 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/O-Nikitin/Social/internal/mailer"
 	"github.com/O-Nikitin/Social/internal/store"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 type userKey string
 
 const userCtx userKey = "user"
 
+// User without password confirms account changes with a recent login
+const confirmLoginAge = 10 * time.Minute
+
+var (
+	errInvalidPassword = errors.New("current password is invalid")
+	errLoginRequired   = errors.New("login again to confirm the action")
+)
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=72"`
+	NewPassword     string `json:"new_password" validate:"required,min=3,max=72"`
+}
+
+// ChangeEmailPayload has current password, it is not required for users without password
+type ChangeEmailPayload struct {
+	Email           string `json:"email" validate:"required,email,max=255"`
+	CurrentPassword string `json:"current_password" validate:"max=72"`
+}
+
+// DeleteAccountPayload has current password. It is not required for users created
+// by OIDC login(has_password is false), they have to login again instead
+type DeleteAccountPayload struct {
+	CurrentPassword string `json:"current_password" validate:"max=72"`
+}
+
+// UpdateProfilePayload changes only fields which are present. Empty string clears the field
+type UpdateProfilePayload struct {
+	DisplayName *string `json:"display_name" validate:"omitempty,max=100"`
+	Bio         *string `json:"bio" validate:"omitempty,max=500"`
+	AvatarURL   *string `json:"avatar_url" validate:"omitempty,max=500,len=0|http_url"`
+	Website     *string `json:"website" validate:"omitempty,max=255,len=0|http_url"`
+	IsPrivate   *bool   `json:"is_private"`
+}
+
+// UserProfile is the public view of the user, email is never shown to other users
+type UserProfile struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	CreatedAt string `json:"created_at"`
+	// Content of suspended user is hidden
+	Suspended bool `json:"suspended"`
+	store.Profile
+	store.Counts
+}
+
+func newUserProfile(user *store.User) UserProfile {
+	return UserProfile{
+		ID:        user.ID,
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt,
+		Suspended: user.Suspension.Active(),
+		Profile:   user.Profile,
+		Counts:    user.Counts,
+	}
+}
+
 // GetUser godoc
 //
 //	@Summary		Get user info
-//	@Description	Get user info by ID
+//	@Description	Get public profile of the user by ID
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			userID	path		int	true	"userID"
-//	@Success		200		{object}	main.envelopeSuccess{data=store.User}
+//	@Success		200		{object}	main.envelopeSuccess{data=main.UserProfile}
 //	@Failure		400		{object}	main.envelopeErr
 //	@Failure		404		{object}	main.envelopeErr
 //	@Failure		500		{object}	main.envelopeErr
@@ -46,21 +106,154 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if err := app.jsonResponse(w, http.StatusOK, newUserProfile(user)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateProfile godoc
+//
+//	@Summary		Update profile
+//	@Description	Updates public profile of the current user. Only fields present in the payload are changed
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateProfilePayload	true	"Profile fields"
+//	@Success		200		{object}	main.envelopeSuccess{data=store.User}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		401		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me [patch]
+func (app *application) updateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateProfilePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	if payload.DisplayName != nil {
+		user.DisplayName = *payload.DisplayName
+	}
+	if payload.Bio != nil {
+		user.Bio = *payload.Bio
+	}
+	if payload.AvatarURL != nil {
+		user.AvatarURL = *payload.AvatarURL
+	}
+	if payload.Website != nil {
+		user.Website = *payload.Website
+	}
+	if payload.IsPrivate != nil {
+		user.IsPrivate = *payload.IsPrivate
+	}
+
+	if err := app.store.Users.UpdateProfile(r.Context(), user); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.invalidateUserCache(r.Context(), user.ID)
+
 	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
 
+// DeleteAccount godoc
+//
+//	@Summary		Delete account
+//	@Description	Schedules deletion of the current user. All sessions and personal access tokens are revoked and the account
+//	@Description	is hidden from other users. Login during the grace period cancels the deletion, after it the account is purged.
+//	@Description	User without password(created by OIDC login) confirms deletion with the session created less than 10 minutes ago
+//	@Tags			users
+//	@Accept			json
+//	@Param			payload	body	DeleteAccountPayload	true	"Current password"
+//	@Success		202		"Deletion scheduled"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		401		{object}	main.envelopeErr	"Login is too old to delete account without password"
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me [delete]
+func (app *application) deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var payload DeleteAccountPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.store.Users.GetByID(r.Context(), getUserFromCtx(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !app.confirmUser(w, r, user, payload.CurrentPassword) {
+		return
+	}
+
+	if err := app.store.Users.ScheduleDeletion(r.Context(), user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.invalidateUserCache(r.Context(), user.ID)
+	app.invalidateSessionsCache(r.Context(), user.ID)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// confirmUser checks the current password before account changes and writes
+// the error response. Password of the user created by OIDC login is unknown
+// to the user, so the recent login with the provider is checked instead
+func (app *application) confirmUser(w http.ResponseWriter, r *http.Request, user *store.User, password string) bool {
+	if user.HasPassword {
+		if err := user.Password.Compare(password); err != nil {
+			app.badRequestResponse(w, r, errInvalidPassword)
+			return false
+		}
+		return true
+	}
+
+	recent, err := app.store.Sessions.LoggedInSince(
+		r.Context(), user.ID, getSessionIDFromCtx(r), time.Now().Add(-confirmLoginAge))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return false
+	}
+	if !recent {
+		app.unauthorizedErrorResponse(w, r, errLoginRequired)
+		return false
+	}
+	return true
+}
+
 // FollowUser godoc
 //
 //	@Summary		Follows a user
-//	@Description	Follows a user by ID
+//	@Description	Follows a user by ID. Follow of a private account creates a follow request which the user has to approve
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			userID	path	int	true	"userID"
+//	@Success		202		"Follow request sent"
 //	@Success		204		"User followed"
 //	@Failure		400		{object}	main.envelopeErr	"User payload missing"
+//	@Failure		403		{object}	main.envelopeErr	"One of the users blocked another"
 //	@Failure		409		{object}	main.envelopeErr	"User already followed"
 //	@Failure		404		{object}	main.envelopeErr
 //	@Failure		500		{object}	main.envelopeErr
@@ -75,25 +268,40 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.store.Followers.Follow(r.Context(), followedUser, currentUser.ID)
+	requested, err := app.store.Followers.Follow(r.Context(), followedUser, currentUser.ID)
 	if err != nil {
 		switch err {
 		case store.ErrConflict:
 			app.conflictResponse(w, r, err)
 			return
+		case store.ErrBlocked:
+			app.forbiddenRepsonse(w, r)
+			return
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+			return
 		default:
 			app.internalServerError(w, r, err)
 			return
 		}
 
 	}
+	app.invalidateSuggestionsCache(r.Context(), currentUser.ID)
+	if requested {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	//Cached users have followers and following counts
+	app.invalidateUserCache(r.Context(), currentUser.ID)
+	app.invalidateUserCache(r.Context(), followedUser)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // UnfollowUser godoc
 //
 //	@Summary		Unfollows a user
-//	@Description	Unfollows a user by ID
+//	@Description	Unfollows a user by ID or cancels pending follow request
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
@@ -122,6 +330,9 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
+	app.invalidateUserCache(r.Context(), currentUser.ID)
+	app.invalidateUserCache(r.Context(), followedUser)
+	app.invalidateSuggestionsCache(r.Context(), currentUser.ID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -154,6 +365,178 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ChangePassword godoc
+//
+//	@Summary		Change password
+//	@Description	Changes password of the current user. All sessions are logged out and a new token pair is returned
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ChangePasswordPayload	true	"Current and new password"
+//	@Success		200		{object}	main.envelopeSuccess{data=main.TokenPair}
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		401		{object}	main.envelopeErr
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/password [patch]
+func (app *application) changePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ChangePasswordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	//User from context can be loaded from cache which has no password hash
+	user, err := app.store.Users.GetByID(r.Context(), getUserFromCtx(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := user.Password.Compare(payload.CurrentPassword); err != nil {
+		app.badRequestResponse(w, r, errInvalidPassword)
+		return
+	}
+
+	if err := user.Password.Set(payload.NewPassword); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Users.ChangePassword(r.Context(), user); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.invalidateSessionsCache(r.Context(), user.ID)
+
+	tokens, err := app.startSession(r, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ChangeEmail godoc
+//
+//	@Summary		Change email
+//	@Description	Sends a confirmation link to the new email. Email is changed only after the link is confirmed.
+//	@Description	User without password(created by OIDC login) confirms the change with the session created less than 10 minutes ago
+//	@Tags			users
+//	@Accept			json
+//	@Param			payload	body	ChangeEmailPayload	true	"New email and current password"
+//	@Success		202		"Confirmation email sent"
+//	@Failure		400		{object}	main.envelopeErr
+//	@Failure		401		{object}	main.envelopeErr	"Login is too old to change email without password"
+//	@Failure		409		{object}	main.envelopeErr	"Email is taken"
+//	@Failure		500		{object}	main.envelopeErr
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/email [patch]
+func (app *application) changeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ChangeEmailPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.store.Users.GetByID(r.Context(), getUserFromCtx(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !app.confirmUser(w, r, user, payload.CurrentPassword) {
+		return
+	}
+
+	plainToken := uuid.New().String()
+	err = app.store.Users.CreateEmailChange(
+		r.Context(), user.ID, payload.Email, plainToken, app.config.mail.exp)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrDuplicateEmail):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	confirmURL := fmt.Sprintf(
+		"%s/confirm-email/%s",
+		app.config.frontendURL,
+		plainToken)
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username   string
+		ConfirmURL string
+	}{
+		Username:   user.Username,
+		ConfirmURL: confirmURL,
+	}
+	//Confirmation goes to the new address to prove that user owns it
+	code, err := app.mailer.Send(
+		mailer.EmailChangeTemplate,
+		user.Username,
+		payload.Email,
+		vars,
+		!isProdEnv)
+	if err != nil {
+		app.logger.Errorw("error sending email change confirmation ", err.Error())
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.logger.Infow("Email sent", "status code", code)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ConfirmEmail godoc
+//
+//	@Summary		Confirms a new email
+//	@Description	Sets the new email of a user by confirmation token
+//	@Tags			users
+//	@Produce		json
+//	@Param			token	path	string	true	"Confirmation token"
+//	@Success		204		"Email changed"
+//	@Failure		404		{object}	main.envelopeErr	"Token is unknown or expired"
+//	@Failure		409		{object}	main.envelopeErr	"Email is taken"
+//	@Failure		500		{object}	main.envelopeErr
+//	@Router			/users/email/confirm/{token} [put]
+func (app *application) confirmEmailHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	userID, err := app.store.Users.ConfirmEmailChange(r.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrDuplicateEmail):
+			//Email was taken by someone else after the token was created
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.invalidateUserCache(r.Context(), userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func getUserFromCtx(r *http.Request) *store.User {
 	user, _ := r.Context().Value(userCtx).(*store.User)
 	return user
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/O-Nikitin/Social/internal/mailer"
 	"github.com/O-Nikitin/Social/internal/store"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
@@ -14,7 +18,6 @@ import (
 func TestUsers_GetUser(t *testing.T) {
 	app, mocks := newTestApp(t, config{})
 	mux := app.mount()
-	testToken := "abc123"
 	t.Run("Should_not_allow_unauthentificated_requests",
 		func(t *testing.T) {
 			req, err := http.NewRequest(
@@ -65,12 +68,611 @@ func TestUsers_GetUser(t *testing.T) {
 			checkContentType("application/json", rr, t)
 
 			var response struct {
-				Data *store.User `json:"data"`
+				Data map[string]any `json:"data"`
 			}
 			json.Unmarshal(rr.Body.Bytes(), &response)
 
-			if !reflect.DeepEqual(expectedUser, response.Data) {
-				t.Errorf("expected user to be %v got %v", expectedUser, response.Data)
+			if _, ok := response.Data["email"]; ok {
+				t.Errorf("expected public profile without email got %v", response.Data)
 			}
+
+			expectedProfile := newUserProfile(expectedUser)
+			var profile UserProfile
+			data, _ := json.Marshal(response.Data)
+			json.Unmarshal(data, &profile)
+			if !reflect.DeepEqual(expectedProfile, profile) {
+				t.Errorf("expected profile to be %v got %v", expectedProfile, profile)
+			}
+		})
+}
+
+func TestUsers_UpdateProfile(t *testing.T) {
+	app, mocks := newTestApp(t, config{redis: redisConfig{enabled: true}})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe", Email: "john@example.com"}
+
+	newRequest := authRequests(app, mocks, user)
+
+	t.Run("Should_not_allow_website_without_http",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPatch, "/v1/users/me", `{"website": "javascript:alert(1)"}`)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_update_profile_and_invalidate_cache",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPatch, "/v1/users/me", `{"display_name": "John", "website": ""}`)
+
+			mocks.Users.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, u *store.User) error {
+					if u.DisplayName != "John" {
+						t.Errorf("expected display name to be %q got %q", "John", u.DisplayName)
+					}
+					return nil
+				})
+			mocks.Cache.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+		})
+}
+
+func TestUsers_Followers(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe"}
+
+	newRequest := authRequests(app, mocks, user)
+
+	t.Run("Should_not_allow_too_big_limit",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/2/followers?limit=1000", "")
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_return_not_found_for_unknown_user",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/2/following", "")
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(nil, store.ErrNotFound)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_return_followers_page",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/2/followers?limit=5&offset=10", "")
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(&store.User{ID: 2}, nil)
+			mocks.Followers.EXPECT().
+				GetFollowers(gomock.Any(), int64(2), store.PaginatedQuery{Limit: 5, Offset: 10}).
+				Return([]store.FollowEntry{{ID: 1, Username: "john_doe"}}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+		})
+
+	t.Run("Should_return_mutual_relationship",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/2/relationship", "")
+			mocks.Followers.EXPECT().GetRelationship(gomock.Any(), int64(1), int64(2)).
+				Return(&store.Relationship{Following: true, FollowedBy: true, Mutual: true}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+
+			var response struct {
+				Data store.Relationship `json:"data"`
+			}
+			json.Unmarshal(rr.Body.Bytes(), &response)
+			if !response.Data.Mutual {
+				t.Errorf("expected mutual relationship got %+v", response.Data)
+			}
+		})
+}
+
+func TestUsers_Block(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe"}
+
+	newRequest := authRequests(app, mocks, user)
+
+	t.Run("Should_not_allow_to_block_yourself",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/users/1/block", "")
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_not_allow_to_follow_blocked_user",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/users/2/follow", "")
+			mocks.Followers.EXPECT().Follow(gomock.Any(), int64(2), int64(1)).Return(false, store.ErrBlocked)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_not_allow_to_comment_post_of_blocked_user",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/posts/10/comments", `{"content": "hi"}`)
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(10)).Return(&store.Post{ID: 10, UserID: 2, Status: store.PostPublished}, nil)
+			mocks.Followers.EXPECT().CanSeePosts(gomock.Any(), int64(1), int64(2)).Return(true, nil)
+			mocks.Blocks.EXPECT().IsBlocked(gomock.Any(), int64(2), int64(1)).Return(true, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_return_conflict_when_already_blocked",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/users/2/block", "")
+			mocks.Blocks.EXPECT().Block(gomock.Any(), int64(1), int64(2)).Return(store.ErrConflict)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusConflict, t)
+		})
+
+	t.Run("Should_return_conflict_when_already_muted",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/users/2/mute", "")
+			mocks.Blocks.EXPECT().Mute(gomock.Any(), int64(1), int64(2)).Return(store.ErrConflict)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusConflict, t)
+		})
+
+	t.Run("Should_return_not_found_when_blocking_unknown_user",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/users/99/block", "")
+			mocks.Blocks.EXPECT().Block(gomock.Any(), int64(1), int64(99)).Return(store.ErrNotFound)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_return_not_found_when_muting_unknown_user",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/users/99/mute", "")
+			mocks.Blocks.EXPECT().Mute(gomock.Any(), int64(1), int64(99)).Return(store.ErrNotFound)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+}
+
+func TestUsers_PrivateAccount(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe"}
+
+	newRequest := authRequests(app, mocks, user)
+
+	t.Run("Should_create_follow_request_for_private_account",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/users/2/follow", "")
+			mocks.Followers.EXPECT().Follow(gomock.Any(), int64(2), int64(1)).Return(true, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusAccepted, t)
+		})
+
+	t.Run("Should_hide_post_of_private_account_from_not_approved_user",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/posts/10", "")
+			mocks.Posts.EXPECT().GetByID(gomock.Any(), int64(10)).Return(&store.Post{ID: 10, UserID: 2, Status: store.PostPublished}, nil)
+			mocks.Followers.EXPECT().CanSeePosts(gomock.Any(), int64(1), int64(2)).Return(false, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_return_not_found_for_unknown_request",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/users/me/follow-requests/2/approve", "")
+			mocks.Followers.EXPECT().ApproveRequest(gomock.Any(), int64(1), int64(2)).Return(store.ErrNotFound)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_not_approve_request_of_blocked_user",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/users/me/follow-requests/2/approve", "")
+			mocks.Followers.EXPECT().ApproveRequest(gomock.Any(), int64(1), int64(2)).Return(store.ErrBlocked)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusForbidden, t)
+		})
+
+	t.Run("Should_return_conflict_for_repeated_request",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/users/2/follow", "")
+			mocks.Followers.EXPECT().Follow(gomock.Any(), int64(2), int64(1)).Return(false, store.ErrConflict)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusConflict, t)
+		})
+}
+
+func TestUsers_DeleteAccount(t *testing.T) {
+	app, mocks := newTestApp(t, config{redis: redisConfig{enabled: true}})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe", HasPassword: true}
+	if err := user.Password.Set("secret"); err != nil {
+		t.Fatal(err)
+	}
+	//Created by OIDC login
+	oidcUser := &store.User{ID: 2, Username: "jane"}
+
+	newRequest := authRequests(app, mocks, user)
+	newOIDCRequest := authRequests(app, mocks, oidcUser)
+
+	t.Run("Should_not_delete_with_wrong_password",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodDelete, "/v1/users/me", `{"current_password": "wrong"}`)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(1)).Return(user, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_schedule_deletion_and_purge_cache",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodDelete, "/v1/users/me", `{"current_password": "secret"}`)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(1)).Return(user, nil)
+			mocks.Users.EXPECT().ScheduleDeletion(gomock.Any(), int64(1)).Return(nil)
+			mocks.Cache.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+			mocks.SessCache.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusAccepted, t)
+		})
+
+	t.Run("Should_require_recent_login_without_password",
+		func(t *testing.T) {
+			req := newOIDCRequest(t, http.MethodDelete, "/v1/users/me", `{}`)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(oidcUser, nil)
+			mocks.Sessions.EXPECT().LoggedInSince(gomock.Any(), int64(2), "", gomock.Any()).Return(false, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusUnauthorized, t)
+		})
+
+	t.Run("Should_delete_after_recent_login_without_password",
+		func(t *testing.T) {
+			req := newOIDCRequest(t, http.MethodDelete, "/v1/users/me", `{}`)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(oidcUser, nil)
+			mocks.Sessions.EXPECT().LoggedInSince(gomock.Any(), int64(2), "", gomock.Any()).Return(true, nil)
+			mocks.Users.EXPECT().ScheduleDeletion(gomock.Any(), int64(2)).Return(nil)
+			mocks.Cache.EXPECT().Delete(gomock.Any(), int64(2)).Return(nil)
+			mocks.SessCache.EXPECT().Delete(gomock.Any(), int64(2)).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusAccepted, t)
+		})
+}
+
+func TestUsers_Search(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe"}
+
+	newRequest := authRequests(app, mocks, user)
+
+	t.Run("Should_require_search_text",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/search?q=%20", "")
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_search_in_autocomplete_mode",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/search?q=jo&autocomplete=true&limit=5", "")
+			mocks.Users.EXPECT().Search(gomock.Any(), int64(1), store.UserSearchQuery{
+				Query:        "jo",
+				Autocomplete: true,
+				Limit:        5,
+			}).Return([]store.UserSummary{{ID: 2, Username: "joe"}}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+		})
+}
+
+func TestUsers_Suggestions(t *testing.T) {
+	app, mocks := newTestApp(t, config{redis: redisConfig{enabled: true}})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe"}
+	suggestions := []store.Suggestion{
+		{ID: 2, Username: "jane", MutualCount: 3},
+		{ID: 3, Username: "bob", MutualCount: 1},
+	}
+
+	newRequest := authRequests(app, mocks, user)
+
+	t.Run("Should_compute_and_cache_on_miss",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/suggestions", "")
+			mocks.SuggCache.EXPECT().Get(gomock.Any(), int64(1)).Return(nil, nil)
+			mocks.Followers.EXPECT().GetSuggestions(gomock.Any(), int64(1), maxSuggestions).Return(suggestions, nil)
+			mocks.SuggCache.EXPECT().Set(gomock.Any(), int64(1), suggestions).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+		})
+
+	t.Run("Should_page_cached_suggestions",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/suggestions?limit=1&offset=1", "")
+			mocks.SuggCache.EXPECT().Get(gomock.Any(), int64(1)).Return(suggestions, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+			if !strings.Contains(rr.Body.String(), `"username":"bob"`) ||
+				strings.Contains(rr.Body.String(), `"username":"jane"`) {
+				t.Errorf("unexpected page: %s", rr.Body.String())
+			}
+		})
+
+	t.Run("Should_reset_on_follow",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPut, "/v1/users/2/follow", "")
+			mocks.Followers.EXPECT().Follow(gomock.Any(), int64(2), int64(1)).Return(false, nil)
+			mocks.SuggCache.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+			mocks.Cache.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+			mocks.Cache.EXPECT().Delete(gomock.Any(), int64(2)).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNoContent, t)
+		})
+}
+
+func TestUsers_Posts(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe"}
+	author := &store.User{ID: 2, Username: "jane"}
+
+	newRequest := authRequests(app, mocks, user)
+
+	t.Run("Should_validate_filters",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/2/posts?sort=random", "")
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_list_posts_with_filters",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/2/posts?tags=go,sql&search=db&sort=asc&limit=5", "")
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(author, nil)
+			mocks.Followers.EXPECT().CanSeePosts(gomock.Any(), int64(1), int64(2)).Return(true, nil)
+			mocks.Blocks.EXPECT().IsBlocked(gomock.Any(), int64(2), int64(1)).Return(false, nil)
+			mocks.Posts.EXPECT().GetByUserID(gomock.Any(), int64(2), store.PaginatedFeedQuery{
+				Limit:  5,
+				Sort:   "asc",
+				Tags:   []string{"go", "sql"},
+				Search: "db",
+			}).Return([]store.PostWithMetadata{{Post: store.Post{ID: 1, UserID: 2}, CommentsCount: 3}}, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusOK, t)
+		})
+
+	t.Run("Should_hide_posts_of_private_user",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/2/posts", "")
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(author, nil)
+			mocks.Followers.EXPECT().CanSeePosts(gomock.Any(), int64(1), int64(2)).Return(false, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_hide_posts_if_blocked",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodGet, "/v1/users/2/posts", "")
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(author, nil)
+			mocks.Followers.EXPECT().CanSeePosts(gomock.Any(), int64(1), int64(2)).Return(true, nil)
+			mocks.Blocks.EXPECT().IsBlocked(gomock.Any(), int64(2), int64(1)).Return(true, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+}
+
+func TestUsers_ChangeEmail(t *testing.T) {
+	app, mocks := newTestApp(t, config{redis: redisConfig{enabled: true}, mail: mailConfig{exp: time.Hour}})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe", Email: "john@example.com", HasPassword: true}
+	if err := user.Password.Set("secret"); err != nil {
+		t.Fatal(err)
+	}
+	//Created by OIDC login
+	oidcUser := &store.User{ID: 2, Username: "jane", Email: "jane@example.com"}
+
+	newRequest := authRequests(app, mocks, user)
+	newOIDCRequest := authRequests(app, mocks, oidcUser)
+
+	t.Run("Should_require_current_password",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPatch, "/v1/users/me/email",
+				`{"email": "new@example.com", "current_password": "wrong"}`)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(1)).Return(user, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_send_confirmation_to_new_email",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPatch, "/v1/users/me/email",
+				`{"email": "new@example.com", "current_password": "secret"}`)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(1)).Return(user, nil)
+			mocks.Users.EXPECT().
+				CreateEmailChange(gomock.Any(), int64(1), "new@example.com", gomock.Any(), time.Hour).
+				Return(nil)
+			mocks.Mailer.EXPECT().
+				Send(mailer.EmailChangeTemplate, "john_doe", "new@example.com", gomock.Any(), gomock.Any()).
+				Return(http.StatusOK, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusAccepted, t)
+		})
+
+	t.Run("Should_change_email_after_recent_login_without_password",
+		func(t *testing.T) {
+			req := newOIDCRequest(t, http.MethodPatch, "/v1/users/me/email", `{"email": "new@example.com"}`)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(oidcUser, nil)
+			mocks.Sessions.EXPECT().LoggedInSince(gomock.Any(), int64(2), "", gomock.Any()).Return(true, nil)
+			mocks.Users.EXPECT().
+				CreateEmailChange(gomock.Any(), int64(2), "new@example.com", gomock.Any(), time.Hour).
+				Return(nil)
+			mocks.Mailer.EXPECT().
+				Send(mailer.EmailChangeTemplate, "jane", "new@example.com", gomock.Any(), gomock.Any()).
+				Return(http.StatusOK, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusAccepted, t)
+		})
+
+	t.Run("Should_not_request_taken_email",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodPatch, "/v1/users/me/email",
+				`{"email": "taken@example.com", "current_password": "secret"}`)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(1)).Return(user, nil)
+			mocks.Users.EXPECT().
+				CreateEmailChange(gomock.Any(), int64(1), "taken@example.com", gomock.Any(), time.Hour).
+				Return(store.ErrDuplicateEmail)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusConflict, t)
+		})
+
+	newConfirm := func(t *testing.T) *http.Request {
+		req, err := http.NewRequest(http.MethodPut, "/v1/users/email/confirm/change-token", nil)
+		if err != nil {
+			t.Fatal("Request not created: ", err)
+		}
+		return req
+	}
+
+	t.Run("Should_confirm_and_invalidate_cache",
+		func(t *testing.T) {
+			mocks.Users.EXPECT().ConfirmEmailChange(gomock.Any(), "change-token").Return(int64(1), nil)
+			mocks.Cache.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+
+			rr := executeRequest(newConfirm(t), mux)
+
+			checkResponseCode(rr.Code, http.StatusNoContent, t)
+		})
+
+	t.Run("Should_reject_expired_token",
+		func(t *testing.T) {
+			mocks.Users.EXPECT().ConfirmEmailChange(gomock.Any(), "change-token").Return(int64(0), store.ErrNotFound)
+
+			rr := executeRequest(newConfirm(t), mux)
+
+			checkResponseCode(rr.Code, http.StatusNotFound, t)
+		})
+
+	t.Run("Should_not_confirm_email_taken_meanwhile",
+		func(t *testing.T) {
+			mocks.Users.EXPECT().ConfirmEmailChange(gomock.Any(), "change-token").Return(int64(0), store.ErrDuplicateEmail)
+
+			rr := executeRequest(newConfirm(t), mux)
+
+			checkResponseCode(rr.Code, http.StatusConflict, t)
+		})
+}
+
+func TestUsers_DisableTwoFactor(t *testing.T) {
+	app, mocks := newTestApp(t, config{})
+	mux := app.mount()
+	user := &store.User{ID: 1, Username: "john_doe", HasPassword: true}
+	if err := user.Password.Set("secret"); err != nil {
+		t.Fatal(err)
+	}
+	//Created by OIDC login
+	oidcUser := &store.User{ID: 2, Username: "jane"}
+
+	newRequest := authRequests(app, mocks, user)
+	newOIDCRequest := authRequests(app, mocks, oidcUser)
+
+	t.Run("Should_not_disable_with_wrong_password",
+		func(t *testing.T) {
+			req := newRequest(t, http.MethodDelete, "/v1/users/me/2fa", `{"current_password": "wrong"}`)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(1)).Return(user, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusBadRequest, t)
+		})
+
+	t.Run("Should_require_recent_login_without_password",
+		func(t *testing.T) {
+			req := newOIDCRequest(t, http.MethodDelete, "/v1/users/me/2fa", `{}`)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(oidcUser, nil)
+			mocks.Sessions.EXPECT().LoggedInSince(gomock.Any(), int64(2), "", gomock.Any()).Return(false, nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusUnauthorized, t)
+		})
+
+	t.Run("Should_disable_after_recent_login_without_password",
+		func(t *testing.T) {
+			req := newOIDCRequest(t, http.MethodDelete, "/v1/users/me/2fa", `{}`)
+			mocks.Users.EXPECT().GetByID(gomock.Any(), int64(2)).Return(oidcUser, nil)
+			mocks.Sessions.EXPECT().LoggedInSince(gomock.Any(), int64(2), "", gomock.Any()).Return(true, nil)
+			mocks.TwoFactor.EXPECT().Disable(gomock.Any(), int64(2)).Return(nil)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(rr.Code, http.StatusNoContent, t)
 		})
 }
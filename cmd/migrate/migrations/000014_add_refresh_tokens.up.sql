@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens(
+    token bytea PRIMARY KEY,
+    user_id bigint NOT NULL,
+    family_id uuid NOT NULL,
+    expiry timestamp(0) with time zone NOT NULL,
+    revoked_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
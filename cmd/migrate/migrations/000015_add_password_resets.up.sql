@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS password_resets(
+    token bytea PRIMARY KEY,
+    user_id bigint NOT NULL,
+    expiry timestamp(0) with time zone NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
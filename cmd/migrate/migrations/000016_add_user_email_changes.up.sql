@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_email_changes(
+    token bytea PRIMARY KEY,
+    user_id bigint NOT NULL,
+    email citext NOT NULL,
+    expiry timestamp(0) with time zone NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
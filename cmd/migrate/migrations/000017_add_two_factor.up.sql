@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS user_totp(
+    user_id bigint PRIMARY KEY,
+    secret text NOT NULL,
+    -- 2FA is enabled only after the first code was confirmed
+    confirmed_at timestamp(0) with time zone,
+    -- protects from reusing the same code twice
+    last_used_step bigint NOT NULL DEFAULT 0,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes(
+    user_id bigint NOT NULL,
+    code bytea NOT NULL,
+    used_at timestamp(0) with time zone,
+
+    PRIMARY KEY(user_id, code),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
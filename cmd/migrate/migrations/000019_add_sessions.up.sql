@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS sessions(
+    -- same as family_id of refresh tokens of the login
+    id uuid PRIMARY KEY,
+    user_id bigint NOT NULL,
+    user_agent varchar(255) NOT NULL DEFAULT '',
+    ip varchar(45) NOT NULL DEFAULT '',
+    expiry timestamp(0) with time zone NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    last_seen_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+-- Logins made before sessions were added
+INSERT INTO sessions (id, user_id, expiry, created_at, last_seen_at)
+SELECT family_id, user_id, MAX(expiry), MIN(created_at), MAX(created_at)
+FROM refresh_tokens
+WHERE revoked_at IS NULL
+GROUP BY family_id, user_id
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS user_identities(
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    -- name of the OIDC provider from config
+    provider varchar(50) NOT NULL,
+    -- "sub" claim of the ID token, unique only within the provider
+    subject varchar(255) NOT NULL,
+    email citext NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    UNIQUE (provider, subject),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS display_name varchar(100) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS bio varchar(500) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS avatar_url varchar(500) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS website varchar(255) NOT NULL DEFAULT '';
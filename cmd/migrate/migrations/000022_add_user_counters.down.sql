@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_followers_follower_id;
+
+DROP TRIGGER IF EXISTS posts_counts ON posts;
+DROP FUNCTION IF EXISTS update_posts_count;
+
+DROP TRIGGER IF EXISTS followers_counts ON followers;
+DROP FUNCTION IF EXISTS update_follow_counts;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS followers_count,
+    DROP COLUMN IF EXISTS following_count,
+    DROP COLUMN IF EXISTS posts_count;
\ No newline at end of file
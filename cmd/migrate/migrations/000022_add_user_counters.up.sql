@@ -0,0 +1,44 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS followers_count bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS following_count bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS posts_count bigint NOT NULL DEFAULT 0;
+
+UPDATE users SET
+    followers_count = (SELECT COUNT(*) FROM followers f WHERE f.follower_id = users.id),
+    following_count = (SELECT COUNT(*) FROM followers f WHERE f.user_id = users.id),
+    posts_count = (SELECT COUNT(*) FROM posts p WHERE p.user_id = users.id);
+
+-- Row followers(user_id, follower_id) means user_id follows follower_id
+CREATE OR REPLACE FUNCTION update_follow_counts() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE users SET following_count = following_count + 1 WHERE id = NEW.user_id;
+        UPDATE users SET followers_count = followers_count + 1 WHERE id = NEW.follower_id;
+    ELSE
+        UPDATE users SET following_count = following_count - 1 WHERE id = OLD.user_id;
+        UPDATE users SET followers_count = followers_count - 1 WHERE id = OLD.follower_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER followers_counts
+AFTER INSERT OR DELETE ON followers
+FOR EACH ROW EXECUTE FUNCTION update_follow_counts();
+
+CREATE OR REPLACE FUNCTION update_posts_count() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE users SET posts_count = posts_count + 1 WHERE id = NEW.user_id;
+    ELSE
+        UPDATE users SET posts_count = posts_count - 1 WHERE id = OLD.user_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER posts_counts
+AFTER INSERT OR DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION update_posts_count();
+
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers (follower_id, created_at);
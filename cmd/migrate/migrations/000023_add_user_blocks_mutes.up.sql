@@ -0,0 +1,24 @@
+-- user_id blocked blocked_id
+CREATE TABLE IF NOT EXISTS user_blocks(
+    user_id bigint NOT NULL,
+    blocked_id bigint NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY(user_id, blocked_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- Block is checked in both directions
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked_id ON user_blocks (blocked_id);
+
+-- user_id muted muted_id
+CREATE TABLE IF NOT EXISTS user_mutes(
+    user_id bigint NOT NULL,
+    muted_id bigint NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY(user_id, muted_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (muted_id) REFERENCES users (id) ON DELETE CASCADE
+);
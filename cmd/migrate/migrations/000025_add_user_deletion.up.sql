@@ -0,0 +1,7 @@
+-- User asked to delete the account, it is purged after the grace period
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone,
+    ADD COLUMN IF NOT EXISTS anonymized_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at)
+WHERE deleted_at IS NOT NULL AND anonymized_at IS NULL;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS data_exports(
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    -- pending, processing, ready or failed
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    -- hash of the token from download link, set when the archive is ready
+    token bytea UNIQUE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    started_at timestamp(0) with time zone,
+    -- archive is removed and link stops working after expiry
+    expiry timestamp(0) with time zone,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports (user_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_status ON data_exports (status)
+WHERE status IN ('pending', 'processing');
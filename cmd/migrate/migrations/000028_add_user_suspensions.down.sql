@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_suspensions;
+
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_until;
\ No newline at end of file
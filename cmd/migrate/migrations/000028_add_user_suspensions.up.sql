@@ -0,0 +1,22 @@
+-- Suspended user can not use the API until suspended_until. Permanent ban is 'infinity'.
+-- Copy of the active suspension, so content of suspended users is filtered without joins
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS suspended_until timestamp(0) with time zone;
+
+-- History of suspensions. expires_at is NULL for permanent ban
+CREATE TABLE IF NOT EXISTS user_suspensions(
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    admin_id bigint,
+    reason text NOT NULL,
+    expires_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    lifted_at timestamp(0) with time zone,
+    lifted_by bigint,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (admin_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (lifted_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_suspensions_user_id ON user_suspensions (user_id);
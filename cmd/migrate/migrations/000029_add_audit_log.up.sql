@@ -0,0 +1,15 @@
+-- Admin actions. Rows are kept when the actor or the target is deleted
+CREATE TABLE IF NOT EXISTS audit_log(
+    id bigserial PRIMARY KEY,
+    actor_id bigint,
+    action varchar(100) NOT NULL,
+    target_user_id bigint,
+    details jsonb NOT NULL DEFAULT '{}',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (target_user_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target_user_id ON audit_log (target_user_id);
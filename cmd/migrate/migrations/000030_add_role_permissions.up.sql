@@ -0,0 +1,28 @@
+-- Permissions allow actions on content of other users and admin actions.
+-- Names are defined in the code, so there is no permissions table
+CREATE TABLE IF NOT EXISTS role_permissions(
+    role_id bigint NOT NULL,
+    permission varchar(100) NOT NULL,
+
+    PRIMARY KEY(role_id, permission),
+    FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE
+);
+
+-- Same rights as the level checks had before. Custom role gets permissions of
+-- every built-in role with the same or lower level
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN roles base ON base.level <= r.level
+JOIN (
+    VALUES
+        ('moderator', 'posts.update.any'),
+        ('moderator', 'comments.delete.any'),
+        ('admin', 'posts.update.any'),
+        ('admin', 'posts.delete.any'),
+        ('admin', 'comments.delete.any'),
+        ('admin', 'users.suspend'),
+        ('admin', 'roles.manage'),
+        ('admin', 'audit.read')
+) AS p(role, permission) ON p.role = base.name
+ON CONFLICT DO NOTHING;
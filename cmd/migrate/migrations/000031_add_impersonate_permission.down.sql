@@ -0,0 +1 @@
+DELETE FROM role_permissions WHERE permission = 'users.impersonate';
\ No newline at end of file
@@ -0,0 +1,3 @@
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'users.impersonate' FROM roles WHERE name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,26 @@
+DROP TRIGGER IF EXISTS posts_counts ON posts;
+
+CREATE OR REPLACE FUNCTION update_posts_count() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE users SET posts_count = posts_count + 1 WHERE id = NEW.user_id;
+    ELSE
+        UPDATE users SET posts_count = posts_count - 1 WHERE id = OLD.user_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER posts_counts
+AFTER INSERT OR DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION update_posts_count();
+
+UPDATE users SET posts_count = (SELECT COUNT(*) FROM posts p WHERE p.user_id = users.id);
+
+DROP INDEX IF EXISTS idx_posts_scheduled;
+
+ALTER TABLE posts
+    DROP CONSTRAINT IF EXISTS posts_publish_at_check,
+    DROP CONSTRAINT IF EXISTS posts_status_check,
+    DROP COLUMN IF EXISTS publish_at,
+    DROP COLUMN IF EXISTS status;
\ No newline at end of file
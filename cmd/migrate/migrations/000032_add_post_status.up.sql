@@ -0,0 +1,31 @@
+-- Drafts are visible only to the author, scheduled posts are published by the
+-- scheduler at publish_at. Published posts have publish_at, the feed is ordered by it
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'published',
+    ADD COLUMN IF NOT EXISTS publish_at timestamp(0) with time zone;
+
+UPDATE posts SET publish_at = created_at WHERE publish_at IS NULL;
+
+ALTER TABLE posts
+    ADD CONSTRAINT posts_status_check CHECK (status IN ('draft', 'scheduled', 'published')),
+    ADD CONSTRAINT posts_publish_at_check CHECK (status = 'draft' OR publish_at IS NOT NULL);
+
+CREATE INDEX IF NOT EXISTS idx_posts_scheduled ON posts (publish_at) WHERE status = 'scheduled';
+
+-- Only published posts are counted
+CREATE OR REPLACE FUNCTION update_posts_count() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.status = 'published' THEN
+        UPDATE users SET posts_count = posts_count + 1 WHERE id = NEW.user_id;
+    END IF;
+    IF TG_OP IN ('DELETE', 'UPDATE') AND OLD.status = 'published' THEN
+        UPDATE users SET posts_count = posts_count - 1 WHERE id = OLD.user_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_counts ON posts;
+CREATE TRIGGER posts_counts
+AFTER INSERT OR DELETE OR UPDATE OF status ON posts
+FOR EACH ROW EXECUTE FUNCTION update_posts_count();
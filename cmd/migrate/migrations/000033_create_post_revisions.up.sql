@@ -0,0 +1,17 @@
+-- Every version of the post title and content. editor_id differs from the
+-- author when moderator edited the post
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    version int NOT NULL,
+    title text NOT NULL,
+    content text NOT NULL,
+    editor_id bigint REFERENCES users (id) ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (post_id, version)
+);
+
+-- Current version of existing posts is the first known revision
+INSERT INTO post_revisions (post_id, version, title, content, editor_id, created_at)
+SELECT id, COALESCE(version, 0), title, content, user_id, updated_at FROM posts
+ON CONFLICT (post_id, version) DO NOTHING;
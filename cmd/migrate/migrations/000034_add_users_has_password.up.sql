@@ -0,0 +1,10 @@
+-- false for users created by OIDC login, their password is random and unknown
+-- to the user until it is set with the password reset
+ALTER TABLE users ADD COLUMN IF NOT EXISTS has_password boolean NOT NULL DEFAULT true;
+
+-- OIDC login creates the user and the identity in one transaction, so NOW() is the same
+UPDATE users SET has_password = false
+WHERE EXISTS (
+    SELECT 1 FROM user_identities ui
+    WHERE ui.user_id = users.id AND ui.created_at = users.created_at
+);
@@ -6,4 +6,6 @@ import "github.com/golang-jwt/jwt/v5"
 type Authenticator interface {
 	GenerateToken(claims jwt.Claims) (string, error)
 	ValidateToken(string) (*jwt.Token, error)
+	// JWKS returns public keys that other services can use to verify tokens
+	JWKS() JWKSet
 }
@@ -38,7 +38,12 @@ func (j *JWTAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
 	},
 		jwt.WithExpirationRequired(),
 		jwt.WithAudience(j.aud),
-		jwt.WithIssuer(j.aud),
+		jwt.WithIssuer(j.iss),
 		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
 	)
 }
+
+// JWKS is always empty because the shared secret can't be published
+func (j *JWTAuthenticator) JWKS() JWKSet {
+	return JWKSet{Keys: []JWK{}}
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySetAuthenticator signs tokens with a private key and verifies them with
+// any of the known public keys. Old keys stay in the set during rotation,
+// so tokens that were signed before the rotation are still valid
+type KeySetAuthenticator struct {
+	signingKey *Key
+	keys       map[string]*Key
+	aud        string
+	iss        string
+}
+
+func NewKeySetAuthenticator(signingKey *Key, verificationKeys []*Key, aud, iss string) (*KeySetAuthenticator, error) {
+	if signingKey == nil || signingKey.private == nil {
+		return nil, errors.New("signing key with private part is required")
+	}
+
+	keys := map[string]*Key{signingKey.ID: signingKey}
+	for _, k := range verificationKeys {
+		if _, ok := keys[k.ID]; ok {
+			return nil, fmt.Errorf("duplicated key id %q", k.ID)
+		}
+		keys[k.ID] = k
+	}
+
+	return &KeySetAuthenticator{
+		signingKey: signingKey,
+		keys:       keys,
+		aud:        aud,
+		iss:        iss}, nil
+}
+
+func (a *KeySetAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(a.signingKey.Method, claims)
+	token.Header["kid"] = a.signingKey.ID
+
+	tokenString, err := token.SignedString(a.signingKey.private)
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+func (a *KeySetAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
+	return jwt.Parse(token, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := a.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		//Key type and token algorithm must match, otherwise alg can be forged
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+
+		return key.public, nil
+	},
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(a.aud),
+		jwt.WithIssuer(a.iss),
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodEdDSA.Alg()}),
+	)
+}
+
+func (a *KeySetAuthenticator) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(a.keys))}
+	for _, k := range a.keys {
+		set.Keys = append(set.Keys, k.JWK())
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
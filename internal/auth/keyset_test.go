@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newRSAKey(t *testing.T, kid string) *Key {
+	t.Helper()
+
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(private),
+	})
+	key, err := ParsePrivateKeyPEM(kid, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func newEd25519Key(t *testing.T, kid string) *Key {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKeyPEM(kid, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+// publicOnly emulates verification key loaded from a public PEM file
+func publicOnly(t *testing.T, k *Key) *Key {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(k.public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePublicKeyPEM(k.ID, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": 1,
+		"exp": time.Now().Add(time.Minute).Unix(),
+		"iss": "Social",
+		"aud": "Social",
+	}
+}
+
+func TestKeySetAuthenticator(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa-1")
+	edKey := newEd25519Key(t, "ed-1")
+
+	t.Run("Should_sign_and_validate", func(t *testing.T) {
+		for _, key := range []*Key{rsaKey, edKey} {
+			a, err := NewKeySetAuthenticator(key, nil, "Social", "Social")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := a.GenerateToken(testClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := a.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("%s: token is not valid: %v", key.Method.Alg(), err)
+			}
+			if parsed.Header["kid"] != key.ID {
+				t.Errorf("expected kid to be %s got %v", key.ID, parsed.Header["kid"])
+			}
+		}
+	})
+
+	t.Run("Should_validate_tokens_of_rotated_key", func(t *testing.T) {
+		old, err := NewKeySetAuthenticator(rsaKey, nil, "Social", "Social")
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := old.GenerateToken(testClaims())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rotated, err := NewKeySetAuthenticator(edKey, []*Key{publicOnly(t, rsaKey)}, "Social", "Social")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rotated.ValidateToken(token); err != nil {
+			t.Errorf("token of rotated key should be valid: %v", err)
+		}
+
+		retired, err := NewKeySetAuthenticator(edKey, nil, "Social", "Social")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := retired.ValidateToken(token); err == nil {
+			t.Error("token of removed key should not be valid")
+		}
+	})
+
+	t.Run("Should_not_allow_HMAC_tokens", func(t *testing.T) {
+		a, err := NewKeySetAuthenticator(rsaKey, nil, "Social", "Social")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hmac := NewJWTAuthenticator("secret", "Social", "Social")
+		token, err := hmac.GenerateToken(testClaims())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := a.ValidateToken(token); err == nil {
+			t.Error("HS256 token should not be valid")
+		}
+	})
+
+	t.Run("Should_publish_all_keys", func(t *testing.T) {
+		a, err := NewKeySetAuthenticator(edKey, []*Key{publicOnly(t, rsaKey)}, "Social", "Social")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		jwks := a.JWKS()
+		if len(jwks.Keys) != 2 {
+			t.Fatalf("expected 2 keys got %d", len(jwks.Keys))
+		}
+		if jwks.Keys[0].Kid != "ed-1" || jwks.Keys[0].Kty != "OKP" || jwks.Keys[0].X == "" {
+			t.Errorf("unexpected Ed25519 key %+v", jwks.Keys[0])
+		}
+		if jwks.Keys[1].Kid != "rsa-1" || jwks.Keys[1].Kty != "RSA" || jwks.Keys[1].E != "AQAB" {
+			t.Errorf("unexpected RSA key %+v", jwks.Keys[1])
+		}
+	})
+}
@@ -0,0 +1,308 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// Keys of the provider are fetched again for unknown kid, but not more often
+	oidcKeysRefreshInterval = time.Minute
+	oidcMaxResponseSize     = 1 << 20
+)
+
+var ErrInvalidNonce = errors.New("invalid nonce in ID token")
+
+// OIDCConfig describes an OpenID Connect identity provider
+type OIDCConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OIDCProvider implements authorization code flow with PKCE(RFC 7636).
+// Provider metadata and keys are fetched on first use, so the API starts
+// even if the provider is not available
+type OIDCProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	metadata      *oidcMetadata
+	keys          map[string]*Key
+	keysFetchedAt time.Time
+}
+
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDTokenClaims are claims of the ID token which are used to find or create the user
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// OIDCLogin holds values which must survive the redirect to the provider
+type OIDCLogin struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+func NewOIDCProvider(cfg OIDCConfig, client *http.Client) *OIDCProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &OIDCProvider{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.cfg.Name
+}
+
+func NewOIDCLogin() (*OIDCLogin, error) {
+	values := make([]string, 3)
+	for i := range values {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	return &OIDCLogin{
+		State:        values[0],
+		Nonce:        values[1],
+		CodeVerifier: values[2],
+	}, nil
+}
+
+// AuthCodeURL returns URL of the provider login page
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, login *OIDCLogin) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(md.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(login.CodeVerifier))
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", login.State)
+	q.Set("nonce", login.Nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// Exchange redeems the authorization code and returns verified claims of the ID token
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, login *OIDCLogin) (*IDTokenClaims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", login.CodeVerifier)
+	form.Set("client_id", p.cfg.ClientID)
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	//client_secret_basic requires form encoding of credentials(RFC 6749 2.3.1)
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.doJSON(req, &tokenResp); err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	if tokenResp.IDToken == "" {
+		return nil, errors.New("no id_token in token response")
+	}
+
+	return p.verifyIDToken(ctx, md, tokenResp.IDToken, login.Nonce)
+}
+
+func (p *OIDCProvider) verifyIDToken(
+	ctx context.Context, md *oidcMetadata, rawToken, nonce string) (*IDTokenClaims, error) {
+
+	claims := &IDTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := p.key(ctx, md, kid)
+		if err != nil {
+			return nil, err
+		}
+		//Key type and token algorithm must match, otherwise alg can be forged
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+
+		return key.public, nil
+	},
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(md.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodES256.Alg(),
+			jwt.SigningMethodEdDSA.Alg()}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	//Nonce binds the token to the login which was started in this browser
+	if claims.Nonce != nonce {
+		return nil, ErrInvalidNonce
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("no subject in ID token")
+	}
+
+	return claims, nil
+}
+
+// discover fetches provider metadata(OpenID Connect Discovery 1.0)
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	issuer := strings.TrimSuffix(p.cfg.IssuerURL, "/")
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var md oidcMetadata
+	if err := p.doJSON(req, &md); err != nil {
+		return nil, fmt.Errorf("discovery of %q failed: %w", p.cfg.Name, err)
+	}
+	//Metadata of another issuer must not be trusted
+	if strings.TrimSuffix(md.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("issuer %q does not match %q", md.Issuer, p.cfg.IssuerURL)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, errors.New("provider metadata is incomplete")
+	}
+
+	p.metadata = &md
+	return p.metadata, nil
+}
+
+// key returns provider key by id. Keys are fetched again if kid is unknown
+// because providers rotate them
+func (p *OIDCProvider) key(ctx context.Context, md *oidcMetadata, kid string) (*Key, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < oidcKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, md.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set JWKSet
+	if err := p.doJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("fetching keys of %q failed: %w", p.cfg.Name, err)
+	}
+	p.keysFetchedAt = time.Now()
+
+	p.keys = make(map[string]*Key, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		//Keys of unsupported types are skipped, provider can have several
+		key, err := jwk.Key()
+		if err != nil {
+			continue
+		}
+		p.keys[key.ID] = key
+	}
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	return key, nil
+}
+
+func (p *OIDCProvider) doJSON(req *http.Request, dst any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	return json.Unmarshal(body, dst)
+}
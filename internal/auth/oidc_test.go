@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockOIDCServer is a minimal identity provider which issues ID token
+// for any code if PKCE verifier matches the challenge of the login
+type mockOIDCServer struct {
+	*httptest.Server
+	key       *Key
+	challenge string
+	nonce     string
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	t.Helper()
+
+	m := &mockOIDCServer{key: newRSAKey(t, "idp-1")}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcMetadata{
+			Issuer:                m.URL,
+			AuthorizationEndpoint: m.URL + "/authorize",
+			TokenEndpoint:         m.URL + "/token",
+			JWKSURI:               m.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JWKSet{Keys: []JWK{m.key.JWK()}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, _ := r.BasicAuth()
+		if clientID != "social" || secret != "secret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(verifier[:]) != m.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		token := jwt.NewWithClaims(m.key.Method, jwt.MapClaims{
+			"iss":            m.URL,
+			"aud":            "social",
+			"sub":            "idp-user-1",
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"nonce":          m.nonce,
+			"email":          "john@example.com",
+			"email_verified": true,
+		})
+		token.Header["kid"] = m.key.ID
+		idToken, err := token.SignedString(m.key.private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+// authorize emulates user login on the provider page
+func (m *mockOIDCServer) authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	m.challenge = q.Get("code_challenge")
+	m.nonce = q.Get("nonce")
+
+	return q
+}
+
+func TestOIDCProvider(t *testing.T) {
+	idp := newMockOIDCServer(t)
+	provider := NewOIDCProvider(OIDCConfig{
+		Name:         "mock",
+		IssuerURL:    idp.URL,
+		ClientID:     "social",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:3000/v1/authentication/oidc/mock/callback",
+	}, idp.Client())
+	ctx := t.Context()
+
+	login, err := NewOIDCLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := provider.AuthCodeURL(ctx, login)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := idp.authorize(t, authURL)
+	if q.Get("state") != login.State || q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected auth URL %s", authURL)
+	}
+
+	t.Run("Should_return_claims_of_ID_token", func(t *testing.T) {
+		claims, err := provider.Exchange(ctx, "code", login)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claims.Subject != "idp-user-1" || claims.Email != "john@example.com" || !claims.EmailVerified {
+			t.Errorf("unexpected claims %+v", claims)
+		}
+	})
+
+	t.Run("Should_fail_with_wrong_code_verifier", func(t *testing.T) {
+		wrong := *login
+		wrong.CodeVerifier = "wrong"
+		if _, err := provider.Exchange(ctx, "code", &wrong); err == nil {
+			t.Error("expected exchange to fail")
+		}
+	})
+
+	t.Run("Should_fail_with_wrong_nonce", func(t *testing.T) {
+		wrong := *login
+		wrong.Nonce = "wrong"
+		if _, err := provider.Exchange(ctx, "code", &wrong); !errors.Is(err, ErrInvalidNonce) {
+			t.Errorf("expected %v got %v", ErrInvalidNonce, err)
+		}
+	})
+}
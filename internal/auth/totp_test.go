@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Secret "12345678901234567890" from RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 Appendix B(SHA1), last 6 digits of the 8-digit values
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.code {
+			t.Errorf("at %d expected code to be %s got %s", tt.unix, tt.code, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := TOTPCode(rfcSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if step, ok := ValidateTOTP(rfcSecret, code, now); !ok || step != now.Unix()/totpPeriod {
+		t.Errorf("expected code to be valid for step %d got %d %v", now.Unix()/totpPeriod, step, ok)
+	}
+	if _, ok := ValidateTOTP(rfcSecret, code, now.Add(totpPeriod*time.Second)); !ok {
+		t.Error("code of previous period should be valid")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, code, now.Add(3*totpPeriod*time.Second)); ok {
+		t.Error("old code should not be valid")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, "000000", now); ok {
+		t.Error("wrong code should not be valid")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Social", "john@example.com", rfcSecret)
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Social:john@example.com?") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+	if !strings.Contains(uri, "secret="+rfcSecret) {
+		t.Errorf("uri %s has no secret", uri)
+	}
+}
@@ -0,0 +1,72 @@
+// Package diff compares texts line by line
+package diff
+
+import "strings"
+
+// Kinds of the line in the diff
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+type Line struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Lines returns the shortest edit which turns a into b. It is built from the
+// longest common subsequence of lines, deleted lines go before inserted ones
+func Lines(a, b string) []Line {
+	x, y := split(a), split(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]Line, 0, len(x)+len(y))
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, Line{Op: Equal, Text: x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: Delete, Text: x[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, Line{Op: Delete, Text: x[i]})
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, Line{Op: Insert, Text: y[j]})
+	}
+
+	return lines
+}
+
+// split returns lines of the text. Empty text has no lines, so adding
+// text to the empty one is shown as inserted lines only
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
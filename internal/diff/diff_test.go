@@ -0,0 +1,58 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Line
+	}{
+		{
+			name: "equal",
+			a:    "one\ntwo",
+			b:    "one\ntwo\n",
+			want: []Line{{Equal, "one"}, {Equal, "two"}},
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "one\ntwo",
+			want: []Line{{Insert, "one"}, {Insert, "two"}},
+		},
+		{
+			name: "to empty",
+			a:    "one",
+			b:    "",
+			want: []Line{{Delete, "one"}},
+		},
+		{
+			name: "changed line",
+			a:    "one\ntwo\nthree",
+			b:    "one\n2\nthree",
+			want: []Line{{Equal, "one"}, {Delete, "two"}, {Insert, "2"}, {Equal, "three"}},
+		},
+		{
+			name: "moved line",
+			a:    "a\nb\nc",
+			b:    "b\nc\na",
+			want: []Line{{Delete, "a"}, {Equal, "b"}, {Equal, "c"}, {Insert, "a"}},
+		},
+		{
+			name: "crlf",
+			a:    "one\r\ntwo\r\n",
+			b:    "one\ntwo\nthree",
+			want: []Line{{Equal, "one"}, {Equal, "two"}, {Insert, "three"}},
+		},
+	}
+
+	for _, tt := range tests {
+		got := Lines(tt.a, tt.b)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 )
 
 func GetString(key, fallback string) string {
@@ -39,3 +40,16 @@ func GetBool(key string, fallback bool) bool {
 	}
 	return res
 }
+
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	res, err := time.ParseDuration(val)
+	if err != nil {
+		fmt.Println(err.Error())
+		return fallback
+	}
+	return res
+}
@@ -3,9 +3,13 @@ package mailer
 import "embed"
 
 const (
-	FromName            = "Social"
-	maxSendingRetries   = 3 //Total time should not be longer than req ctx timeout!!!
-	UserWelcomeTemplate = "user_invitation.tmpl"
+	FromName              = "Social"
+	maxSendingRetries     = 3 //Total time should not be longer than req ctx timeout!!!
+	UserWelcomeTemplate   = "user_invitation.tmpl"
+	PasswordResetTemplate = "password_reset.tmpl"
+	EmailChangeTemplate   = "email_change.tmpl"
+	LoginAlertTemplate    = "login_alert.tmpl"
+	DataExportTemplate    = "data_export.tmpl"
 )
 
 //go:embed "template"
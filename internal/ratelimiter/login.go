@@ -0,0 +1,93 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxLoginEntries limits memory of MemoryLoginThrottler, expired entries
+// are removed when the limit is reached
+const maxLoginEntries = 10_000
+
+type loginEntry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// MemoryLoginThrottler works only for a single server instance,
+// RedisLoginThrottler should be used otherwise
+type MemoryLoginThrottler struct {
+	sync.Mutex
+	cfg     LoginConfig
+	entries map[string]*loginEntry
+	now     func() time.Time
+}
+
+func NewMemoryLoginThrottler(cfg LoginConfig) *MemoryLoginThrottler {
+	return &MemoryLoginThrottler{
+		cfg:     cfg,
+		entries: make(map[string]*loginEntry),
+		now:     time.Now,
+	}
+}
+
+func (t *MemoryLoginThrottler) Locked(_ context.Context, key string) (time.Duration, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	entry, ok := t.entries[key]
+	if !ok {
+		return 0, nil
+	}
+
+	return max(entry.lockedUntil.Sub(t.now()), 0), nil
+}
+
+func (t *MemoryLoginThrottler) Fail(_ context.Context, key string, maxAttempts int) (time.Duration, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	now := t.now()
+	entry, ok := t.entries[key]
+	if !ok || t.expired(entry, now) {
+		if len(t.entries) >= maxLoginEntries {
+			t.removeExpired(now)
+		}
+		entry = &loginEntry{}
+		t.entries[key] = entry
+	}
+
+	entry.failures++
+	entry.lastFailure = now
+
+	lock := t.cfg.lockout(entry.failures, maxAttempts)
+	if lock > 0 {
+		entry.lockedUntil = now.Add(lock)
+	}
+
+	return lock, nil
+}
+
+func (t *MemoryLoginThrottler) Reset(_ context.Context, key string) error {
+	t.Lock()
+	delete(t.entries, key)
+	t.Unlock()
+
+	return nil
+}
+
+// Entry is kept while it is locked and for Window after the last failure,
+// so failures after the lock make the next lock longer
+func (t *MemoryLoginThrottler) expired(entry *loginEntry, now time.Time) bool {
+	return now.After(entry.lockedUntil) && now.Sub(entry.lastFailure) > t.cfg.Window
+}
+
+func (t *MemoryLoginThrottler) removeExpired(now time.Time) {
+	for key, entry := range t.entries {
+		if t.expired(entry, now) {
+			delete(t.entries, key)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisLoginThrottler shares failed attempts between all server instances
+type RedisLoginThrottler struct {
+	rdb *redis.Client
+	cfg LoginConfig
+}
+
+func NewRedisLoginThrottler(rdb *redis.Client, cfg LoginConfig) *RedisLoginThrottler {
+	return &RedisLoginThrottler{
+		rdb: rdb,
+		cfg: cfg,
+	}
+}
+
+func (t *RedisLoginThrottler) Locked(ctx context.Context, key string) (time.Duration, error) {
+	if t.rdb == nil {
+		return 0, errors.New("redis cache disabled in config")
+	}
+
+	ttl, err := t.rdb.PTTL(ctx, lockKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	//Negative values mean that key does not exist or has no expiry
+	return max(ttl, 0), nil
+}
+
+func (t *RedisLoginThrottler) Fail(ctx context.Context, key string, maxAttempts int) (time.Duration, error) {
+	if t.rdb == nil {
+		return 0, errors.New("redis cache disabled in config")
+	}
+
+	failures, err := t.rdb.Incr(ctx, failuresKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	lock := t.cfg.lockout(int(failures), maxAttempts)
+
+	pipe := t.rdb.TxPipeline()
+	//Failures are kept while the key is locked and for Window after it
+	pipe.PExpire(ctx, failuresKey(key), t.cfg.Window+lock)
+	if lock > 0 {
+		pipe.Set(ctx, lockKey(key), failures, lock)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	return lock, nil
+}
+
+func (t *RedisLoginThrottler) Reset(ctx context.Context, key string) error {
+	if t.rdb == nil {
+		return errors.New("redis cache disabled in config")
+	}
+
+	return t.rdb.Del(ctx, failuresKey(key), lockKey(key)).Err()
+}
+
+func failuresKey(key string) string {
+	return fmt.Sprintf("login-failures-%s", key)
+}
+
+func lockKey(key string) string {
+	return fmt.Sprintf("login-lock-%s", key)
+}
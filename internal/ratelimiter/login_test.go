@@ -0,0 +1,53 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLoginThrottler(t *testing.T) {
+	cfg := LoginConfig{
+		Window:      time.Minute * 15,
+		BaseLockout: time.Minute,
+		MaxLockout:  time.Minute * 5,
+	}
+	now := time.Unix(1_700_000_000, 0)
+	throttler := NewMemoryLoginThrottler(cfg)
+	throttler.now = func() time.Time { return now }
+	ctx := context.Background()
+	key := "account:john@example.com"
+
+	for i := 1; i < 3; i++ {
+		if lock, _ := throttler.Fail(ctx, key, 3); lock != 0 {
+			t.Fatalf("attempt %d should not lock, got %s", i, lock)
+		}
+	}
+
+	// Lock doubles with every failure after the limit and stops at MaxLockout
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+		lock, _ := throttler.Fail(ctx, key, 3)
+		if lock != want {
+			t.Fatalf("expected lock to be %s got %s", want, lock)
+		}
+
+		if locked, _ := throttler.Locked(ctx, key); locked != want {
+			t.Errorf("expected key to be locked for %s got %s", want, locked)
+		}
+		now = now.Add(want)
+		if locked, _ := throttler.Locked(ctx, key); locked != 0 {
+			t.Errorf("expected lock to expire got %s", locked)
+		}
+	}
+
+	// Failures are forgotten after Window
+	now = now.Add(cfg.Window + time.Second)
+	if lock, _ := throttler.Fail(ctx, key, 3); lock != 0 {
+		t.Errorf("failures should be forgotten after window, got lock %s", lock)
+	}
+
+	throttler.Reset(ctx, key)
+	if locked, _ := throttler.Locked(ctx, key); locked != 0 {
+		t.Errorf("expected key to be unlocked after reset got %s", locked)
+	}
+}
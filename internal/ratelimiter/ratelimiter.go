@@ -1,8 +1,11 @@
 package ratelimiter
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
-//go:generate mockgen -source=./ratelimiter.go -destination=../../cmd/api/mock/ratelimiter/Mock_RateLimiter.go -package=mock_limiter Limiter
+//go:generate mockgen -source=./ratelimiter.go -destination=../../cmd/api/mock/ratelimiter/Mock_RateLimiter.go -package=mock_limiter Limiter,LoginThrottler
 type Limiter interface {
 	Allow(ip string) (bool, time.Duration)
 }
@@ -12,3 +15,40 @@ type Config struct {
 	TimeFrame            time.Duration
 	Enabled              bool
 }
+
+// LoginThrottler counts failed logins per key(account, IP) and locks the key
+// with exponential backoff when there are too many failures
+type LoginThrottler interface {
+	// Locked returns for how long the key is still locked
+	Locked(ctx context.Context, key string) (time.Duration, error)
+	// Fail registers a failed attempt and returns lock duration
+	// if the key got locked because of it
+	Fail(ctx context.Context, key string, maxAttempts int) (time.Duration, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type LoginConfig struct {
+	MaxAccountAttempts int
+	MaxIPAttempts      int
+	// Failures are forgotten after Window without new failures
+	Window time.Duration
+	// First lock is BaseLockout, every next failure doubles it up to MaxLockout
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	// Send an email to the account owner when the account gets locked
+	NotifyUser bool
+	Enabled    bool
+}
+
+func (c LoginConfig) lockout(failures, maxAttempts int) time.Duration {
+	if failures < maxAttempts {
+		return 0
+	}
+
+	lock := c.BaseLockout
+	for i := maxAttempts; i < failures && lock < c.MaxLockout; i++ {
+		lock *= 2
+	}
+
+	return min(lock, c.MaxLockout)
+}
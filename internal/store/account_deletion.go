@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// userDataTables have data of the user which is removed when the account is
+// anonymized. Posts and comments are kept, but posts are hidden like the posts
+// of any deleted account
+var userDataTables = []string{
+	"refresh_tokens",
+	"sessions",
+	"personal_access_tokens",
+	"password_resets",
+	"user_email_changes",
+	"user_totp",
+	"user_recovery_codes",
+	"user_identities",
+	"user_invitations",
+	"data_exports",
+	"user_mutes",
+	"user_blocks",
+	"follow_requests",
+	"followers",
+}
+
+// ScheduleDeletion marks the account as deleted. All sessions and personal
+// access tokens are revoked, the account is hidden from other users and
+// purged by PurgeDeleted after the grace period
+func (u *UserStore) ScheduleDeletion(ctx context.Context, userID int64) error {
+	if u.db == nil {
+		return errors.New("nil db in UserStore")
+	}
+
+	return withTx(u.db, ctx, func(tx *sql.Tx) error {
+		query := `UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+		res, err := tx.ExecContext(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		if err := revokeUserSessions(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		query = `DELETE FROM personal_access_tokens WHERE user_id = $1`
+		_, err = tx.ExecContext(ctx, query, userID)
+		return err
+	})
+}
+
+// CancelDeletion restores the account which was not purged yet
+func (u *UserStore) CancelDeletion(ctx context.Context, userID int64) error {
+	if u.db == nil {
+		return errors.New("nil db in UserStore")
+	}
+	const query = `
+		UPDATE users SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL AND anonymized_at IS NULL
+	`
+
+	return execAffected(ctx, u.db, query, userID)
+}
+
+// PurgeDeleted removes accounts which were deleted earlier than gracePeriod ago.
+// If anonymize is true posts and comments are kept, but the user row loses
+// all personal data, otherwise the user is deleted with posts and comments
+func (u *UserStore) PurgeDeleted(
+	ctx context.Context, gracePeriod time.Duration, anonymize bool) (int64, error) {
+	if u.db == nil {
+		return 0, errors.New("nil db in UserStore")
+	}
+
+	var purged int64
+	err := withTx(u.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT id FROM users
+			WHERE deleted_at < $1 AND anonymized_at IS NULL
+			FOR UPDATE
+		`
+		rows, err := tx.QueryContext(ctx, query, time.Now().Add(-gracePeriod))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		userIDs := []int64{}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			userIDs = append(userIDs, id)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		purged = int64(len(userIDs))
+		if purged == 0 {
+			return nil
+		}
+
+		if anonymize {
+			return anonymizeUsers(ctx, tx, userIDs)
+		}
+		return deleteUsers(ctx, tx, userIDs)
+	})
+
+	return purged, err
+}
+
+func anonymizeUsers(ctx context.Context, tx *sql.Tx, userIDs []int64) error {
+	//Empty hash never matches a password
+	query := `
+		UPDATE users SET
+			username = 'deleted_' || id,
+			email = 'deleted_' || id || '@deleted.invalid',
+			password = ''::bytea,
+			display_name = '',
+			bio = '',
+			avatar_url = '',
+			website = '',
+			is_private = false,
+			anonymized_at = NOW()
+		WHERE id = ANY($1)
+	`
+	if _, err := tx.ExecContext(ctx, query, pq.Array(userIDs)); err != nil {
+		return err
+	}
+
+	for _, table := range userDataTables {
+		query := `DELETE FROM ` + table + ` WHERE user_id = ANY($1)`
+		if _, err := tx.ExecContext(ctx, query, pq.Array(userIDs)); err != nil {
+			return err
+		}
+	}
+
+	//Rows where the user is the other side
+	for _, query := range []string{
+		`DELETE FROM followers WHERE follower_id = ANY($1)`,
+		`DELETE FROM follow_requests WHERE target_id = ANY($1)`,
+		`DELETE FROM user_blocks WHERE blocked_id = ANY($1)`,
+		`DELETE FROM user_mutes WHERE muted_id = ANY($1)`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, pq.Array(userIDs)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteUsers(ctx context.Context, tx *sql.Tx, userIDs []int64) error {
+	//Comments and posts have no cascade delete. Other tables are removed by foreign keys
+	for _, query := range []string{
+		`DELETE FROM comments WHERE user_id = ANY($1)
+			OR post_id IN (SELECT id FROM posts WHERE user_id = ANY($1))`,
+		`DELETE FROM posts WHERE user_id = ANY($1)`,
+		`DELETE FROM user_invitations WHERE user_id = ANY($1)`,
+		`DELETE FROM users WHERE id = ANY($1)`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, pq.Array(userIDs)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// Actions in the audit log
+const (
+	AuditRoleAssigned    = "role.assigned"
+	AuditRoleCreated     = "role.created"
+	AuditRolePermissions = "role.permissions"
+	// Token to act as another user was issued
+	AuditImpersonationStarted = "impersonation.started"
+	// Write request was made with impersonation token
+	AuditImpersonatedWrite = "impersonation.write"
+)
+
+// AuditEntry is an admin action. Details depend on the action
+type AuditEntry struct {
+	ID           int64           `json:"id"`
+	ActorID      *int64          `json:"actor_id"`
+	Action       string          `json:"action"`
+	TargetUserID *int64          `json:"target_user_id,omitempty"`
+	Details      json.RawMessage `json:"details" swaggertype:"object"`
+	CreatedAt    string          `json:"created_at"`
+}
+
+type AuditStore struct {
+	db *sql.DB
+}
+
+// GetAll returns audit log, the newest actions first
+func (s *AuditStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]AuditEntry, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in AuditStore")
+	}
+	const query = `
+		SELECT id, actor_id, action, target_user_id, details, created_at
+		FROM audit_log
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Limit, pq.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		err := rows.Scan(
+			&e.ID,
+			&e.ActorID,
+			&e.Action,
+			&e.TargetUserID,
+			&e.Details,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// Create records the action which is not part of a transaction
+func (s *AuditStore) Create(ctx context.Context, entry *AuditEntry) error {
+	if s.db == nil {
+		return errors.New("nil db in AuditStore")
+	}
+	const query = `
+		INSERT INTO audit_log (actor_id, action, target_user_id, details)
+		VALUES ($1, $2, $3, COALESCE($4::jsonb, '{}'))
+		RETURNING id, created_at
+	`
+
+	var details any
+	if entry.Details != nil {
+		details = []byte(entry.Details)
+	}
+	return s.db.QueryRowContext(
+		ctx, query, entry.ActorID, entry.Action, entry.TargetUserID, details,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// writeAudit records the action in the same transaction as the change itself
+func writeAudit(
+	ctx context.Context, tx *sql.Tx, actorID int64, action string, targetUserID *int64, details any) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO audit_log (actor_id, action, target_user_id, details)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err = tx.ExecContext(ctx, query, actorID, action, targetUserID, data)
+	return err
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrBlocked is returned when one of the users blocked another
+var ErrBlocked = errors.New("user is blocked")
+
+type BlockStore struct {
+	db *sql.DB
+}
+
+// Block blocks blockedID for userID. Follows and pending follow requests in both
+// directions are removed in the same transaction, so blocked user does not see
+// posts in the feed and an old request can not be approved
+func (b *BlockStore) Block(ctx context.Context, userID int64, blockedID int64) error {
+	if b.db == nil {
+		return errors.New("nil db in BlockStore")
+	}
+
+	return withTx(b.db, ctx, func(tx *sql.Tx) error {
+		query := `INSERT INTO user_blocks (user_id, blocked_id) VALUES ($1, $2)`
+		if _, err := tx.ExecContext(ctx, query, userID, blockedID); err != nil {
+			if _, ok := pgError(err, pgUniqueViolation); ok {
+				return ErrConflict
+			}
+			//Blocked user does not exist
+			if _, ok := pgError(err, pgForeignKeyViolation); ok {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		query = `
+			DELETE FROM followers
+			WHERE (user_id = $1 AND follower_id = $2) OR (user_id = $2 AND follower_id = $1)
+		`
+		if _, err := tx.ExecContext(ctx, query, userID, blockedID); err != nil {
+			return err
+		}
+
+		query = `
+			DELETE FROM follow_requests
+			WHERE (user_id = $1 AND target_id = $2) OR (user_id = $2 AND target_id = $1)
+		`
+		_, err := tx.ExecContext(ctx, query, userID, blockedID)
+		return err
+	})
+}
+
+func (b *BlockStore) Unblock(ctx context.Context, userID int64, blockedID int64) error {
+	if b.db == nil {
+		return errors.New("nil db in BlockStore")
+	}
+	const query = `DELETE FROM user_blocks WHERE user_id = $1 AND blocked_id = $2`
+
+	return execAffected(ctx, b.db, query, userID, blockedID)
+}
+
+// Mute hides posts of mutedID from the feed of userID, muted user is not notified
+func (b *BlockStore) Mute(ctx context.Context, userID int64, mutedID int64) error {
+	if b.db == nil {
+		return errors.New("nil db in BlockStore")
+	}
+	const query = `INSERT INTO user_mutes (user_id, muted_id) VALUES ($1, $2)`
+
+	if _, err := b.db.ExecContext(ctx, query, userID, mutedID); err != nil {
+		if _, ok := pgError(err, pgUniqueViolation); ok {
+			return ErrConflict
+		}
+		if _, ok := pgError(err, pgForeignKeyViolation); ok {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (b *BlockStore) Unmute(ctx context.Context, userID int64, mutedID int64) error {
+	if b.db == nil {
+		return errors.New("nil db in BlockStore")
+	}
+	const query = `DELETE FROM user_mutes WHERE user_id = $1 AND muted_id = $2`
+
+	return execAffected(ctx, b.db, query, userID, mutedID)
+}
+
+// IsBlocked reports if any of the users blocked another
+func (b *BlockStore) IsBlocked(ctx context.Context, userID int64, otherID int64) (bool, error) {
+	if b.db == nil {
+		return false, errors.New("nil db in BlockStore")
+	}
+	const query = `
+		SELECT EXISTS(
+			SELECT 1 FROM user_blocks
+			WHERE (user_id = $1 AND blocked_id = $2) OR (user_id = $2 AND blocked_id = $1)
+		)
+	`
+
+	var blocked bool
+	err := b.db.QueryRowContext(ctx, query, userID, otherID).Scan(&blocked)
+	return blocked, err
+}
+
+// execAffected returns ErrNotFound if query has not changed any row
+func execAffected(ctx context.Context, db *sql.DB, query string, args ...any) error {
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/redis/go-redis/v9"
+)
+
+// RoleExpTime can be long, because changed role is removed from cache
+const RoleExpTime = time.Hour
+
+type RoleStore struct {
+	rdb *redis.Client
+}
+
+// Get returns nil if the role is not in cache
+func (s *RoleStore) Get(ctx context.Context, roleID int64) (*store.Role, error) {
+	if s.rdb == nil {
+		return nil, errors.New("redis cache disabled in config")
+	}
+	cacheKey := fmt.Sprintf("role-%d", roleID)
+	data, err := s.rdb.Get(ctx, cacheKey).Result()
+	if err == redis.Nil { //Key not exists
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var role store.Role
+	if err := json.Unmarshal([]byte(data), &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (s *RoleStore) Set(ctx context.Context, role *store.Role) error {
+	if s.rdb == nil {
+		return errors.New("redis cache disabled in config")
+	}
+	cacheKey := fmt.Sprintf("role-%d", role.ID)
+
+	json, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.Set(ctx, cacheKey, json, RoleExpTime).Err()
+}
+
+func (s *RoleStore) Delete(ctx context.Context, roleID int64) error {
+	if s.rdb == nil {
+		return errors.New("redis cache disabled in config")
+	}
+	cacheKey := fmt.Sprintf("role-%d", roleID)
+
+	return s.rdb.Del(ctx, cacheKey).Err()
+}
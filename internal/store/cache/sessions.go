@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SessionExpTime is short because expired sessions are not removed from cache
+const SessionExpTime = time.Minute * 15
+
+type SessionStore struct {
+	rdb *redis.Client
+}
+
+// Get returns nil if sessions of the user are not in cache
+func (s *SessionStore) Get(ctx context.Context, userID int64) ([]string, error) {
+	if s.rdb == nil {
+		return nil, errors.New("redis cache disabled in config")
+	}
+	cacheKey := fmt.Sprintf("sessions-%d", userID)
+	data, err := s.rdb.Get(ctx, cacheKey).Result()
+	if err == redis.Nil { //Key not exists
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	sessionIDs := []string{}
+	if err := json.Unmarshal([]byte(data), &sessionIDs); err != nil {
+		return nil, err
+	}
+	return sessionIDs, nil
+}
+
+func (s *SessionStore) Set(ctx context.Context, userID int64, sessionIDs []string) error {
+	if s.rdb == nil {
+		return errors.New("redis cache disabled in config")
+	}
+	cacheKey := fmt.Sprintf("sessions-%d", userID)
+
+	json, err := json.Marshal(sessionIDs)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.Set(ctx, cacheKey, json, SessionExpTime).Err()
+}
+
+func (s *SessionStore) Delete(ctx context.Context, userID int64) error {
+	if s.rdb == nil {
+		return errors.New("redis cache disabled in config")
+	}
+	cacheKey := fmt.Sprintf("sessions-%d", userID)
+
+	return s.rdb.Del(ctx, cacheKey).Err()
+}
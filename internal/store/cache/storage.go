@@ -7,19 +7,49 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-//go:generate mockgen -source=./storage.go -destination=../../../cmd/api/mock/store/Mock_Cache.go -package=mock_storage -mock_names Users=MockUserCache Users
+//go:generate mockgen -source=./storage.go -destination=../../../cmd/api/mock/store/Mock_Cache.go -package=mock_storage -mock_names Users=MockUserCache,Sessions=MockSessionCache,Suggestions=MockSuggestionCache,Roles=MockRoleCache Users,Sessions,Suggestions,Roles
 type Users interface {
 	Get(context.Context, int64) (*store.User, error)
 	Set(context.Context, *store.User) error
+	Delete(context.Context, int64) error
+}
+
+// Sessions keeps IDs of active sessions of the user,
+// so access tokens are checked without DB
+type Sessions interface {
+	Get(context.Context, int64) ([]string, error)
+	Set(context.Context, int64, []string) error
+	Delete(context.Context, int64) error
+}
+
+// Suggestions keeps users recommended to follow, because
+// they are expensive to compute
+type Suggestions interface {
+	Get(context.Context, int64) ([]store.Suggestion, error)
+	Set(context.Context, int64, []store.Suggestion) error
+	Delete(context.Context, int64) error
+}
+
+// Roles keeps permissions of roles, which are checked on every request
+type Roles interface {
+	Get(context.Context, int64) (*store.Role, error)
+	Set(context.Context, *store.Role) error
+	Delete(context.Context, int64) error
 }
 
 type Storage struct {
 	//TODO add for posts also
-	Users Users
+	Users       Users
+	Sessions    Sessions
+	Suggestions Suggestions
+	Roles       Roles
 }
 
 func NewStorage(rdb *redis.Client) Storage {
 	return Storage{
-		Users: &UserStore{rdb: rdb},
+		Users:       &UserStore{rdb: rdb},
+		Sessions:    &SessionStore{rdb: rdb},
+		Suggestions: &SuggestionStore{rdb: rdb},
+		Roles:       &RoleStore{rdb: rdb},
 	}
 }
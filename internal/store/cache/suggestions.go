@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/O-Nikitin/Social/internal/store"
+	"github.com/redis/go-redis/v9"
+)
+
+// SuggestionExpTime limits how long new activity of other users is not
+// visible in suggestions. Own follows and blocks remove them from cache
+const SuggestionExpTime = time.Hour
+
+type SuggestionStore struct {
+	rdb *redis.Client
+}
+
+// Get returns nil if suggestions for the user are not in cache
+func (s *SuggestionStore) Get(ctx context.Context, userID int64) ([]store.Suggestion, error) {
+	if s.rdb == nil {
+		return nil, errors.New("redis cache disabled in config")
+	}
+	cacheKey := fmt.Sprintf("suggestions-%d", userID)
+	data, err := s.rdb.Get(ctx, cacheKey).Result()
+	if err == redis.Nil { //Key not exists
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	suggestions := []store.Suggestion{}
+	if err := json.Unmarshal([]byte(data), &suggestions); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+func (s *SuggestionStore) Set(ctx context.Context, userID int64, suggestions []store.Suggestion) error {
+	if s.rdb == nil {
+		return errors.New("redis cache disabled in config")
+	}
+	cacheKey := fmt.Sprintf("suggestions-%d", userID)
+
+	json, err := json.Marshal(suggestions)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.Set(ctx, cacheKey, json, SuggestionExpTime).Err()
+}
+
+func (s *SuggestionStore) Delete(ctx context.Context, userID int64) error {
+	if s.rdb == nil {
+		return errors.New("redis cache disabled in config")
+	}
+	cacheKey := fmt.Sprintf("suggestions-%d", userID)
+
+	return s.rdb.Del(ctx, cacheKey).Err()
+}
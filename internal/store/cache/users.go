@@ -51,3 +51,12 @@ func (u *UserStore) Set(ctx context.Context, user *store.User) error {
 
 	return u.rdb.Set(ctx, cacheKey, json, UserExpTime).Err()
 }
+
+func (u *UserStore) Delete(ctx context.Context, userID int64) error {
+	if u.rdb == nil {
+		return errors.New("redis cache disabled in config")
+	}
+	cacheKey := fmt.Sprintf("user-%d", userID)
+
+	return u.rdb.Del(ctx, cacheKey).Err()
+}
@@ -53,7 +53,7 @@ func (c *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment
 	query := `
 		select c.id, c.post_id, c.user_id, c.content, c.created_at, users.username, users.id from comments c
 		join users on users.id = c.user_id
-		where c.post_id = $1
+		where c.post_id = $1 and (users.suspended_until is null or users.suspended_until < now())
 		order by c.created_at DESC;
 		`
 
@@ -78,3 +78,42 @@ func (c *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment
 
 	return comments, nil
 }
+
+func (c *CommentStore) GetByID(ctx context.Context, commentID int64) (*Comment, error) {
+	if c.db == nil {
+		return nil, errors.New("nil db in CommentsStore")
+	}
+	const query = `
+		SELECT id, post_id, user_id, content, created_at
+		FROM comments
+		WHERE id = $1
+	`
+
+	var comment Comment
+	err := c.db.QueryRowContext(ctx, query, commentID).Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.UserID,
+		&comment.Content,
+		&comment.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &comment, nil
+}
+
+func (c *CommentStore) Delete(ctx context.Context, commentID int64) error {
+	if c.db == nil {
+		return errors.New("nil db in CommentsStore")
+	}
+	const query = `DELETE FROM comments WHERE id = $1`
+
+	return execAffected(ctx, c.db, query, commentID)
+}
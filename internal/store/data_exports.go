@@ -0,0 +1,412 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrExportLimited = errors.New("data export was already requested recently")
+
+// Statuses of the data export
+const (
+	ExportPending    = "pending"
+	ExportProcessing = "processing"
+	ExportReady      = "ready"
+	ExportFailed     = "failed"
+)
+
+// Export which is processing longer than this is considered abandoned by
+// stopped instance and is claimed again
+const exportProcessingTimeout = 15 * time.Minute
+
+// DataExport is the request of the user to get archive with own data
+type DataExport struct {
+	ID        int64   `json:"id"`
+	UserID    int64   `json:"user_id"`
+	Status    string  `json:"status"`
+	CreatedAt string  `json:"created_at"`
+	Expiry    *string `json:"expires_at,omitempty"`
+}
+
+// UserData is everything stored about the user
+type UserData struct {
+	Profile   User
+	Posts     []ExportedPost
+	Comments  []ExportedComment
+	Followers []FollowEntry
+	Following []FollowEntry
+	Sessions  []Session
+}
+
+type ExportedPost struct {
+	ID        int64    `json:"id"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Tags      []string `json:"tags"`
+	Status    string   `json:"status"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+type ExportedComment struct {
+	ID        int64  `json:"id"`
+	PostID    int64  `json:"post_id"`
+	Content   string `json:"content"`
+	CreatedAt string `json:"created_at"`
+}
+
+type DataExportStore struct {
+	db *sql.DB
+}
+
+// Create stores a pending export. ErrExportLimited is returned if the user
+// requested another export less than minInterval ago
+func (s *DataExportStore) Create(
+	ctx context.Context, export *DataExport, minInterval time.Duration) error {
+	if s.db == nil {
+		return errors.New("nil db in DataExportStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		//Lock of the user row serializes concurrent requests of the same user
+		query := `SELECT id FROM users WHERE id = $1 FOR UPDATE`
+		if _, err := tx.ExecContext(ctx, query, export.UserID); err != nil {
+			return err
+		}
+
+		var limited bool
+		query = `
+			SELECT EXISTS(
+				SELECT 1 FROM data_exports
+				WHERE user_id = $1 AND created_at > $2 AND status <> $3
+			)
+		`
+		err := tx.QueryRowContext(
+			ctx, query, export.UserID, time.Now().Add(-minInterval), ExportFailed).Scan(&limited)
+		if err != nil {
+			return err
+		}
+		if limited {
+			return ErrExportLimited
+		}
+
+		query = `
+			INSERT INTO data_exports (user_id) VALUES ($1)
+			RETURNING id, status, created_at
+		`
+		return tx.QueryRowContext(ctx, query, export.UserID).Scan(
+			&export.ID,
+			&export.Status,
+			&export.CreatedAt,
+		)
+	})
+}
+
+// ClaimPending marks the oldest pending export as processing and returns it.
+// Several instances can process exports, every export is claimed only by one.
+// ErrNotFound is returned if there is nothing to process
+func (s *DataExportStore) ClaimPending(ctx context.Context) (*DataExport, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in DataExportStore")
+	}
+	const query = `
+		UPDATE data_exports SET status = $1, started_at = NOW()
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = $2 OR (status = $1 AND started_at < $3)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, status, created_at
+	`
+
+	var export DataExport
+	err := s.db.QueryRowContext(
+		ctx, query, ExportProcessing, ExportPending, time.Now().Add(-exportProcessingTimeout),
+	).Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Status,
+		&export.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &export, nil
+}
+
+// Finish sets the final status of the export. Ready export can be downloaded
+// with the token until exp passes, token is ignored for failed export
+func (s *DataExportStore) Finish(
+	ctx context.Context, export *DataExport, token string, exp time.Duration) error {
+	if s.db == nil {
+		return errors.New("nil db in DataExportStore")
+	}
+	const query = `
+		UPDATE data_exports SET status = $1, token = $2, expiry = $3
+		WHERE id = $4
+		RETURNING expiry
+	`
+
+	var tokenHash, expiry any
+	if export.Status == ExportReady {
+		tokenHash = hashToken(token)
+		expiry = time.Now().Add(exp)
+	}
+	err := s.db.QueryRowContext(
+		ctx, query, export.Status, tokenHash, expiry, export.ID).Scan(&export.Expiry)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// GetByToken returns ready export which link has not expired
+func (s *DataExportStore) GetByToken(ctx context.Context, token string) (*DataExport, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in DataExportStore")
+	}
+	const query = `
+		SELECT id, user_id, status, created_at, expiry
+		FROM data_exports
+		WHERE token = $1 AND status = $2 AND expiry > NOW()
+	`
+
+	var export DataExport
+	err := s.db.QueryRowContext(ctx, query, hashToken(token), ExportReady).Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Status,
+		&export.CreatedAt,
+		&export.Expiry,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &export, nil
+}
+
+// Collect reads all data of the user from one snapshot of DB
+func (s *DataExportStore) Collect(ctx context.Context, userID int64) (*UserData, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in DataExportStore")
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	data := &UserData{}
+	collectors := []func(context.Context, *sql.Tx, int64, *UserData) error{
+		collectProfile,
+		collectPosts,
+		collectComments,
+		collectFollows,
+		collectSessions,
+	}
+	for _, collect := range collectors {
+		if err := collect(ctx, tx, userID, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, tx.Commit()
+}
+
+func collectProfile(ctx context.Context, tx *sql.Tx, userID int64, data *UserData) error {
+	const query = `
+		SELECT
+			u.id, u.email, u.username, u.created_at, u.is_active,
+			u.display_name, u.bio, u.avatar_url, u.website, u.is_private,
+			u.followers_count, u.following_count, u.posts_count,
+			r.id, r.name, r.level, r.description
+		FROM users u
+		JOIN roles r ON r.id = u.role_id
+		WHERE u.id = $1
+	`
+
+	user := &data.Profile
+	err := tx.QueryRowContext(ctx, query, userID).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.CreatedAt,
+		&user.IsActive,
+		&user.DisplayName,
+		&user.Bio,
+		&user.AvatarURL,
+		&user.Website,
+		&user.IsPrivate,
+		&user.FollowersCount,
+		&user.FollowingCount,
+		&user.PostsCount,
+		&user.Role.ID,
+		&user.Role.Name,
+		&user.Role.Level,
+		&user.Role.Description,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	user.RoleID = user.Role.ID
+	return err
+}
+
+func collectPosts(ctx context.Context, tx *sql.Tx, userID int64, data *UserData) error {
+	const query = `
+		SELECT id, title, content, tags, status, created_at, updated_at
+		FROM posts
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	data.Posts = []ExportedPost{}
+	for rows.Next() {
+		var p ExportedPost
+		err := rows.Scan(
+			&p.ID,
+			&p.Title,
+			&p.Content,
+			pq.Array(&p.Tags),
+			&p.Status,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		data.Posts = append(data.Posts, p)
+	}
+
+	return rows.Err()
+}
+
+func collectComments(ctx context.Context, tx *sql.Tx, userID int64, data *UserData) error {
+	const query = `
+		SELECT id, post_id, content, created_at
+		FROM comments
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	data.Comments = []ExportedComment{}
+	for rows.Next() {
+		var c ExportedComment
+		if err := rows.Scan(&c.ID, &c.PostID, &c.Content, &c.CreatedAt); err != nil {
+			return err
+		}
+		data.Comments = append(data.Comments, c)
+	}
+
+	return rows.Err()
+}
+
+func collectFollows(ctx context.Context, tx *sql.Tx, userID int64, data *UserData) error {
+	//Row followers(user_id, follower_id) means user_id follows follower_id
+	const followersQuery = `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, f.created_at
+		FROM followers f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.follower_id = $1
+		ORDER BY f.created_at
+	`
+	const followingQuery = `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, f.created_at
+		FROM followers f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.user_id = $1
+		ORDER BY f.created_at
+	`
+
+	var err error
+	if data.Followers, err = collectFollowEntries(ctx, tx, followersQuery, userID); err != nil {
+		return err
+	}
+	data.Following, err = collectFollowEntries(ctx, tx, followingQuery, userID)
+	return err
+}
+
+func collectFollowEntries(ctx context.Context, tx *sql.Tx, query string, userID int64) ([]FollowEntry, error) {
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []FollowEntry{}
+	for rows.Next() {
+		var e FollowEntry
+		if err := rows.Scan(&e.ID, &e.Username, &e.DisplayName, &e.AvatarURL, &e.FollowedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+func collectSessions(ctx context.Context, tx *sql.Tx, userID int64, data *UserData) error {
+	const query = `
+		SELECT id, user_id, user_agent, ip, expiry, created_at, last_seen_at
+		FROM sessions
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	data.Sessions = []Session{}
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IP,
+			&session.Expiry,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+		)
+		if err != nil {
+			return err
+		}
+		data.Sessions = append(data.Sessions, session)
+	}
+
+	return rows.Err()
+}
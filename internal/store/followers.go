@@ -4,8 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-
-	"github.com/lib/pq"
 )
 
 type Follower struct {
@@ -18,46 +16,89 @@ type FollowersStore struct {
 	db *sql.DB
 }
 
-func (f *FollowersStore) Follow(ctx context.Context, followerID int64, currentUserID int64) error {
+// Follow makes currentUserID follower of followerID. Follow of a private account
+// creates a follow request instead and true is returned
+func (f *FollowersStore) Follow(ctx context.Context, followerID int64, currentUserID int64) (bool, error) {
 	if f.db == nil {
-		return errors.New("nil db in FollowersStore")
+		return false, errors.New("nil db in FollowersStore")
 	}
-	const query = `
-	INSERT INTO followers (user_id, follower_id)
-	VALUES ($1, $2)
-	`
 
-	_, err := f.db.ExecContext(ctx, query, currentUserID,
-		followerID)
-	if err != nil {
-		// 23505 = unique violation (already following)
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+	requested := false
+	err := withTx(f.db, ctx, func(tx *sql.Tx) error {
+		var isPrivate, blocked, following bool
+		query := `
+			SELECT
+				u.is_private,
+				EXISTS(
+					SELECT 1 FROM user_blocks
+					WHERE (user_id = $1 AND blocked_id = $2) OR (user_id = $2 AND blocked_id = $1)
+				),
+				EXISTS(SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)
+			FROM users u
+			WHERE u.id = $2 AND u.is_active = true AND u.deleted_at IS NULL
+		`
+		err := tx.QueryRowContext(ctx, query, currentUserID, followerID).Scan(
+			&isPrivate,
+			&blocked,
+			&following,
+		)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		case err != nil:
+			return err
+		case blocked:
+			return ErrBlocked
+		case following:
 			return ErrConflict
 		}
-		return err
+
+		query = `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`
+		if isPrivate {
+			query = `INSERT INTO follow_requests (user_id, target_id) VALUES ($1, $2)`
+			requested = true
+		}
+		if _, err := tx.ExecContext(ctx, query, currentUserID, followerID); err != nil {
+			//Already following or requested
+			if _, ok := pgError(err, pgUniqueViolation); ok {
+				return ErrConflict
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
 	}
-	return nil
+
+	return requested, nil
 }
 
+// Unfollow removes the follow or cancels pending follow request
 func (f *FollowersStore) Unfollow(ctx context.Context, followerID int64, currentUserID int64) error {
 
 	if f.db == nil {
 		return errors.New("nil db in FollowersStore")
 	}
 	const query = `
-	DELETE FROM followers
-	WHERE user_id = $1 AND follower_id = $2
+	WITH follow AS (
+		DELETE FROM followers
+		WHERE user_id = $1 AND follower_id = $2
+		RETURNING 1
+	), request AS (
+		DELETE FROM follow_requests
+		WHERE user_id = $1 AND target_id = $2
+		RETURNING 1
+	)
+	SELECT (SELECT COUNT(*) FROM follow) + (SELECT COUNT(*) FROM request)
 	`
 
-	res, err := f.db.ExecContext(ctx, query, currentUserID, followerID)
+	var rows int64
+	err := f.db.QueryRowContext(ctx, query, currentUserID, followerID).Scan(&rows)
 	if err != nil {
 		return err
 	}
 
-	rows, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
 	//TODO Maybe it is not needed if client interface designed well
 	// and it is not possible to click unfollow on user that current
 	// is not following at the moment. Can be changed when UI ready
@@ -67,3 +108,336 @@ func (f *FollowersStore) Unfollow(ctx context.Context, followerID int64, current
 
 	return nil
 }
+
+// FollowRequest is the user who asked to follow private account
+type FollowRequest struct {
+	UserID      int64  `json:"user_id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// GetRequests returns pending follow requests to userID, oldest first
+func (f *FollowersStore) GetRequests(ctx context.Context, userID int64, q PaginatedQuery) ([]FollowRequest, error) {
+	if f.db == nil {
+		return nil, errors.New("nil db in FollowersStore")
+	}
+	const query = `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, fr.created_at
+		FROM follow_requests fr
+		JOIN users u ON u.id = fr.user_id
+		WHERE fr.target_id = $1 AND u.is_active = true AND u.deleted_at IS NULL
+		ORDER BY fr.created_at, u.id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := f.db.QueryContext(ctx, query, userID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []FollowRequest{}
+	for rows.Next() {
+		var fr FollowRequest
+		err := rows.Scan(
+			&fr.UserID,
+			&fr.Username,
+			&fr.DisplayName,
+			&fr.AvatarURL,
+			&fr.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, fr)
+	}
+
+	return requests, rows.Err()
+}
+
+// ApproveRequest turns follow request of requesterID into follow of userID.
+// ErrBlocked is returned if one of the users blocked another
+func (f *FollowersStore) ApproveRequest(ctx context.Context, userID int64, requesterID int64) error {
+	if f.db == nil {
+		return errors.New("nil db in FollowersStore")
+	}
+
+	return withTx(f.db, ctx, func(tx *sql.Tx) error {
+		query := `DELETE FROM follow_requests WHERE user_id = $1 AND target_id = $2`
+		res, err := tx.ExecContext(ctx, query, requesterID, userID)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		var blocked bool
+		query = `
+			SELECT EXISTS(
+				SELECT 1 FROM user_blocks
+				WHERE (user_id = $1 AND blocked_id = $2) OR (user_id = $2 AND blocked_id = $1)
+			)
+		`
+		if err := tx.QueryRowContext(ctx, query, userID, requesterID).Scan(&blocked); err != nil {
+			return err
+		}
+		if blocked {
+			return ErrBlocked
+		}
+
+		query = `
+			INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`
+		_, err = tx.ExecContext(ctx, query, requesterID, userID)
+		return err
+	})
+}
+
+func (f *FollowersStore) RejectRequest(ctx context.Context, userID int64, requesterID int64) error {
+	if f.db == nil {
+		return errors.New("nil db in FollowersStore")
+	}
+	const query = `DELETE FROM follow_requests WHERE user_id = $1 AND target_id = $2`
+
+	return execAffected(ctx, f.db, query, requesterID, userID)
+}
+
+// CanSeePosts reports if viewerID can see posts of authorID.
+// Posts of private account are visible only to the author and approved followers,
+// posts of suspended or deleted account are hidden from everyone. Anonymized
+// account keeps deleted_at, so its posts stay hidden as in the feeds
+func (f *FollowersStore) CanSeePosts(ctx context.Context, viewerID int64, authorID int64) (bool, error) {
+	if f.db == nil {
+		return false, errors.New("nil db in FollowersStore")
+	}
+	const query = `
+		SELECT
+			u.deleted_at IS NULL AND
+			(u.suspended_until IS NULL OR u.suspended_until < NOW()) AND (
+				NOT u.is_private OR u.id = $1 OR
+				EXISTS(SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = u.id)
+			)
+		FROM users u
+		WHERE u.id = $2
+	`
+
+	var allowed bool
+	err := f.db.QueryRowContext(ctx, query, viewerID, authorID).Scan(&allowed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return allowed, nil
+}
+
+// FollowEntry is the user in followers or following list
+type FollowEntry struct {
+	ID          int64  `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	FollowedAt  string `json:"followed_at"`
+}
+
+// Relationship describes follows, block and mute of another user by the current one
+type Relationship struct {
+	Following  bool `json:"following"`
+	FollowedBy bool `json:"followed_by"`
+	Mutual     bool `json:"mutual"`
+	Requested  bool `json:"requested"`
+	Blocking   bool `json:"blocking"`
+	Muting     bool `json:"muting"`
+}
+
+// GetFollowers returns users who follow userID, newest first
+func (f *FollowersStore) GetFollowers(ctx context.Context, userID int64, q PaginatedQuery) ([]FollowEntry, error) {
+	if f.db == nil {
+		return nil, errors.New("nil db in FollowersStore")
+	}
+	const query = `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, f.created_at
+		FROM followers f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.follower_id = $1 AND u.is_active = true AND u.deleted_at IS NULL
+		ORDER BY f.created_at DESC, u.id
+		LIMIT $2 OFFSET $3
+	`
+
+	return f.list(ctx, query, userID, q)
+}
+
+// GetFollowing returns users who are followed by userID, newest first
+func (f *FollowersStore) GetFollowing(ctx context.Context, userID int64, q PaginatedQuery) ([]FollowEntry, error) {
+	if f.db == nil {
+		return nil, errors.New("nil db in FollowersStore")
+	}
+	const query = `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, f.created_at
+		FROM followers f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.user_id = $1 AND u.is_active = true AND u.deleted_at IS NULL
+		ORDER BY f.created_at DESC, u.id
+		LIMIT $2 OFFSET $3
+	`
+
+	return f.list(ctx, query, userID, q)
+}
+
+func (f *FollowersStore) list(
+	ctx context.Context, query string, userID int64, q PaginatedQuery) ([]FollowEntry, error) {
+
+	rows, err := f.db.QueryContext(ctx, query, userID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []FollowEntry{}
+	for rows.Next() {
+		var e FollowEntry
+		err := rows.Scan(
+			&e.ID,
+			&e.Username,
+			&e.DisplayName,
+			&e.AvatarURL,
+			&e.FollowedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetRelationship returns follows between currentUserID and userID in both directions
+func (f *FollowersStore) GetRelationship(
+	ctx context.Context, currentUserID int64, userID int64) (*Relationship, error) {
+	if f.db == nil {
+		return nil, errors.New("nil db in FollowersStore")
+	}
+	const query = `
+		SELECT
+			EXISTS(SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2),
+			EXISTS(SELECT 1 FROM followers WHERE user_id = $2 AND follower_id = $1),
+			EXISTS(SELECT 1 FROM follow_requests WHERE user_id = $1 AND target_id = $2),
+			EXISTS(SELECT 1 FROM user_blocks WHERE user_id = $1 AND blocked_id = $2),
+			EXISTS(SELECT 1 FROM user_mutes WHERE user_id = $1 AND muted_id = $2)
+	`
+
+	var rel Relationship
+	err := f.db.QueryRowContext(ctx, query, currentUserID, userID).Scan(
+		&rel.Following,
+		&rel.FollowedBy,
+		&rel.Requested,
+		&rel.Blocking,
+		&rel.Muting,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rel.Mutual = rel.Following && rel.FollowedBy
+
+	return &rel, nil
+}
+
+// Suggestion is the user recommended to follow
+type Suggestion struct {
+	ID             int64  `json:"id"`
+	Username       string `json:"username"`
+	DisplayName    string `json:"display_name"`
+	AvatarURL      string `json:"avatar_url"`
+	FollowersCount int64  `json:"followers_count"`
+	// Followed users of the current user who follow this one
+	MutualCount int64 `json:"mutual_count"`
+}
+
+// Popular accounts are added to candidates, so users who follow nobody yet get suggestions too
+const suggestionPopularPool = 100
+
+// GetSuggestions recommends users followed by the users whom userID follows.
+// More mutual connections and posts in the last 30 days rank the user higher.
+// Already followed, requested, blocked and muted users are excluded
+func (f *FollowersStore) GetSuggestions(ctx context.Context, userID int64, limit int) ([]Suggestion, error) {
+	if f.db == nil {
+		return nil, errors.New("nil db in FollowersStore")
+	}
+	const query = `
+		WITH following AS (
+			SELECT follower_id AS id FROM followers WHERE user_id = $1
+		), candidates AS (
+			SELECT f.follower_id AS id, COUNT(*) AS mutual
+			FROM followers f
+			JOIN following fo ON fo.id = f.user_id
+			GROUP BY f.follower_id
+			UNION ALL
+			(
+				SELECT id, 0 FROM users
+				WHERE is_active = true AND deleted_at IS NULL AND
+					(suspended_until IS NULL OR suspended_until < NOW())
+				ORDER BY followers_count DESC
+				LIMIT $3
+			)
+		)
+		SELECT
+			u.id, u.username, u.display_name, u.avatar_url, u.followers_count,
+			MAX(c.mutual) AS mutual
+		FROM candidates c
+		JOIN users u ON u.id = c.id
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS posts FROM posts p
+			WHERE p.user_id = u.id AND p.status = 'published' AND p.publish_at > NOW() - INTERVAL '30 days'
+		) recent ON true
+		WHERE
+			u.id <> $1 AND
+			u.is_active = true AND u.deleted_at IS NULL AND
+			(u.suspended_until IS NULL OR u.suspended_until < NOW()) AND
+			NOT EXISTS (SELECT 1 FROM following fo WHERE fo.id = u.id) AND
+			NOT EXISTS (SELECT 1 FROM follow_requests fr WHERE fr.user_id = $1 AND fr.target_id = u.id) AND
+			NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.user_id = $1 AND b.blocked_id = u.id) OR (b.user_id = u.id AND b.blocked_id = $1)
+			) AND
+			NOT EXISTS (SELECT 1 FROM user_mutes m WHERE m.user_id = $1 AND m.muted_id = u.id)
+		GROUP BY u.id, recent.posts
+		ORDER BY MAX(c.mutual) + LN(1 + recent.posts) DESC, u.followers_count DESC, u.id
+		LIMIT $2
+	`
+
+	rows, err := f.db.QueryContext(ctx, query, userID, limit, suggestionPopularPool)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		var s Suggestion
+		err := rows.Scan(
+			&s.ID,
+			&s.Username,
+			&s.DisplayName,
+			&s.AvatarURL,
+			&s.FollowersCount,
+			&s.MutualCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions, rows.Err()
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrEmailNotVerified = errors.New("email is not verified by the identity provider")
+
+// Identity links the user to an account of an external OIDC provider
+type Identity struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+type IdentityStore struct {
+	db *sql.DB
+}
+
+// FindOrCreateUser sets user.ID to the user linked to the identity. Unknown identity
+// is linked to the active user with the same email, otherwise user is created as
+// already activated. Both need the email verified by the provider, ErrEmailNotVerified
+// is returned for unknown identity with not verified email.
+// Not activated user with the same email is not linked because its password
+// was set by someone who did not confirm the email, ErrDuplicateEmail is returned
+func (s *IdentityStore) FindOrCreateUser(
+	ctx context.Context, identity *Identity, user *User, emailVerified bool) error {
+	if s.db == nil {
+		return errors.New("nil db in IdentityStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		//deleted_at is returned, so login can cancel deletion of the account
+		query := `
+			SELECT ui.user_id, u.deleted_at
+			FROM user_identities ui
+			JOIN users u ON u.id = ui.user_id
+			WHERE ui.provider = $1 AND ui.subject = $2
+		`
+		err := tx.QueryRowContext(ctx, query, identity.Provider, identity.Subject).Scan(
+			&identity.UserID,
+			&user.DeletedAt,
+		)
+		switch {
+		case err == nil:
+			user.ID = identity.UserID
+			return nil
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+
+		//Anyone can claim an email at some providers, so such user can't own it here
+		if !emailVerified {
+			return ErrEmailNotVerified
+		}
+
+		linked := false
+		query = `SELECT id, deleted_at FROM users WHERE email = $1 AND is_active = true`
+		err = tx.QueryRowContext(ctx, query, user.Email).Scan(&user.ID, &user.DeletedAt)
+		switch {
+		case err == nil:
+			linked = true
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+
+		if !linked {
+			users := &UserStore{db: s.db}
+			if err := users.Create(ctx, tx, user); err != nil {
+				return err
+			}
+			//Email is confirmed by the provider, so the user does not need activation.
+			//Password of the user is random, it can be set with the password reset
+			query := `UPDATE users SET is_active = true, has_password = false WHERE id = $1`
+			if _, err := tx.ExecContext(ctx, query, user.ID); err != nil {
+				return err
+			}
+			user.IsActive = true
+			user.HasPassword = false
+		}
+
+		identity.UserID = user.ID
+		query = `
+			INSERT INTO user_identities (user_id, provider, subject, email)
+			VALUES ($1, $2, $3, $4) RETURNING id, created_at
+		`
+		return tx.QueryRowContext(
+			ctx,
+			query,
+			identity.UserID,
+			identity.Provider,
+			identity.Subject,
+			identity.Email,
+		).Scan(
+			&identity.ID,
+			&identity.CreatedAt,
+		)
+	})
+}
@@ -76,3 +76,69 @@ func parseTime(s string) string {
 	}
 	return t.Format(time.DateTime)
 }
+
+// PaginatedQuery is used for lists which have no filters
+type PaginatedQuery struct {
+	Limit  int `json:"limit" validate:"gte=1,lte=50"`
+	Offset int `json:"offset" validate:"gte=0"`
+}
+
+func (pq PaginatedQuery) Parse(r *http.Request) (PaginatedQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return pq, err
+		}
+
+		pq.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return pq, err
+		}
+
+		pq.Offset = o
+	}
+
+	return pq, nil
+}
+
+// UserSearchQuery searches users by username and display name.
+// Autocomplete matches only by prefix and ignores offset
+type UserSearchQuery struct {
+	Query        string `json:"q" validate:"required,max=100"`
+	Autocomplete bool   `json:"autocomplete"`
+	Limit        int    `json:"limit" validate:"gte=1,lte=50"`
+	Offset       int    `json:"offset" validate:"gte=0"`
+}
+
+func (sq UserSearchQuery) Parse(r *http.Request) (UserSearchQuery, error) {
+	qs := r.URL.Query()
+
+	sq.Query = strings.TrimSpace(qs.Get("q"))
+
+	autocomplete := qs.Get("autocomplete")
+	if autocomplete != "" {
+		a, err := strconv.ParseBool(autocomplete)
+		if err != nil {
+			return sq, err
+		}
+
+		sq.Autocomplete = a
+	}
+
+	page, err := PaginatedQuery{Limit: sq.Limit, Offset: sq.Offset}.Parse(r)
+	if err != nil {
+		return sq, err
+	}
+	sq.Limit = page.Limit
+	sq.Offset = page.Offset
+
+	return sq, nil
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Scopes limit what a personal access token can do
+const (
+	ScopePostsRead     = "posts:read"
+	ScopePostsWrite    = "posts:write"
+	ScopeCommentsWrite = "comments:write"
+	ScopeFeedRead      = "feed:read"
+	ScopeUsersRead     = "users:read"
+	ScopeUsersWrite    = "users:write"
+)
+
+// Scopes are all scopes which can be given to a personal access token
+var Scopes = []string{
+	ScopePostsRead,
+	ScopePostsWrite,
+	ScopeCommentsWrite,
+	ScopeFeedRead,
+	ScopeUsersRead,
+	ScopeUsersWrite,
+}
+
+type PersonalToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  string     `json:"created_at"`
+}
+
+type PersonalTokenStore struct {
+	db *sql.DB
+}
+
+func (s *PersonalTokenStore) Create(ctx context.Context, token string, pt *PersonalToken) error {
+	if s.db == nil {
+		return errors.New("nil db in PersonalTokenStore")
+	}
+
+	const query = `
+		INSERT INTO personal_access_tokens (user_id, name, token, scopes, expiry)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at
+	`
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		pt.UserID,
+		pt.Name,
+		hashToken(token),
+		pq.Array(pt.Scopes),
+		pt.ExpiresAt,
+	).Scan(
+		&pt.ID,
+		&pt.CreatedAt,
+	)
+}
+
+func (s *PersonalTokenStore) GetByUserID(ctx context.Context, userID int64) ([]PersonalToken, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in PersonalTokenStore")
+	}
+
+	const query = `
+		SELECT id, user_id, name, scopes, expiry, last_used_at, created_at
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []PersonalToken{}
+	for rows.Next() {
+		var pt PersonalToken
+		err := rows.Scan(
+			&pt.ID,
+			&pt.UserID,
+			&pt.Name,
+			pq.Array(&pt.Scopes),
+			&pt.ExpiresAt,
+			&pt.LastUsedAt,
+			&pt.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, pt)
+	}
+
+	return tokens, rows.Err()
+}
+
+// Authenticate returns not expired token and updates the time it was last used
+func (s *PersonalTokenStore) Authenticate(ctx context.Context, token string) (*PersonalToken, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in PersonalTokenStore")
+	}
+
+	const query = `
+		UPDATE personal_access_tokens SET last_used_at = NOW()
+		WHERE token = $1 AND (expiry IS NULL OR expiry > NOW())
+		RETURNING id, user_id, name, scopes, expiry, last_used_at, created_at
+	`
+
+	var pt PersonalToken
+	err := s.db.QueryRowContext(ctx, query, hashToken(token)).Scan(
+		&pt.ID,
+		&pt.UserID,
+		&pt.Name,
+		pq.Array(&pt.Scopes),
+		&pt.ExpiresAt,
+		&pt.LastUsedAt,
+		&pt.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &pt, nil
+}
+
+func (s *PersonalTokenStore) Delete(ctx context.Context, userID, tokenID int64) error {
+	if s.db == nil {
+		return errors.New("nil db in PersonalTokenStore")
+	}
+
+	const query = `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`
+
+	res, err := s.db.ExecContext(ctx, query, tokenID, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// PostRevision is one saved version of the post. EditorID is nil when the
+// editor account was purged
+type PostRevision struct {
+	ID             int64   `json:"id"`
+	PostID         int64   `json:"post_id"`
+	Version        int     `json:"version"`
+	Title          string  `json:"title"`
+	Content        string  `json:"content"`
+	EditorID       *int64  `json:"editor_id"`
+	EditorUsername *string `json:"editor_username"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+type PostRevisionStore struct {
+	db *sql.DB
+}
+
+// GetByPostID returns revisions of the post, the newest first
+func (s *PostRevisionStore) GetByPostID(ctx context.Context, postID int64, q PaginatedQuery) ([]PostRevision, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in PostRevisionStore")
+	}
+
+	const query = `
+		SELECT r.id, r.post_id, r.version, r.title, r.content, r.editor_id, u.username, r.created_at
+		FROM post_revisions r
+		LEFT JOIN users u ON u.id = r.editor_id
+		WHERE r.post_id = $1
+		ORDER BY r.version DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, postID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []PostRevision{}
+	for rows.Next() {
+		var r PostRevision
+		err := rows.Scan(
+			&r.ID,
+			&r.PostID,
+			&r.Version,
+			&r.Title,
+			&r.Content,
+			&r.EditorID,
+			&r.EditorUsername,
+			&r.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (s *PostRevisionStore) GetByVersion(ctx context.Context, postID int64, version int) (*PostRevision, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in PostRevisionStore")
+	}
+
+	const query = `
+		SELECT r.id, r.post_id, r.version, r.title, r.content, r.editor_id, u.username, r.created_at
+		FROM post_revisions r
+		LEFT JOIN users u ON u.id = r.editor_id
+		WHERE r.post_id = $1 AND r.version = $2
+	`
+
+	var r PostRevision
+	err := s.db.QueryRowContext(ctx, query, postID, version).Scan(
+		&r.ID,
+		&r.PostID,
+		&r.Version,
+		&r.Title,
+		&r.Content,
+		&r.EditorID,
+		&r.EditorUsername,
+		&r.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// writeRevision keeps the current title and content of the post. It is
+// called in the transaction which created or updated the post
+func writeRevision(ctx context.Context, tx *sql.Tx, post *Post, editorID int64) error {
+	const query = `
+		INSERT INTO post_revisions (post_id, version, title, content, editor_id)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := tx.ExecContext(ctx, query, post.ID, post.Version, post.Title, post.Content, editorID)
+	return err
+}
@@ -8,12 +8,22 @@ import (
 	"github.com/lib/pq"
 )
 
+// Statuses of the post. Draft and scheduled posts are visible only to the author
+const (
+	PostDraft     = "draft"
+	PostScheduled = "scheduled"
+	PostPublished = "published"
+)
+
 type Post struct {
-	ID        int64     `json:"id"`
-	Content   string    `json:"content"`
-	Title     string    `json:"title"`
-	UserID    int64     `json:"user_id"`
-	Tags      []string  `json:"tags"`
+	ID      int64    `json:"id"`
+	Content string   `json:"content"`
+	Title   string   `json:"title"`
+	UserID  int64    `json:"user_id"`
+	Tags    []string `json:"tags"`
+	Status  string   `json:"status"`
+	// Time when scheduled post is published or published post was published
+	PublishAt *string   `json:"publish_at"`
 	CreatedAt string    `json:"created_at"`
 	UpdatedAt string    `json:"updated_at"`
 	Version   int       `json:"version"`
@@ -35,23 +45,39 @@ func (p *PostStore) Create(
 	if p.db == nil {
 		return errors.New("nil db in PostStore")
 	}
+	//Published post gets publish_at of the moment it was created
 	const query = `
-	   INSERT INTO posts (content, title, user_id, tags)
-	   VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at 
+	   INSERT INTO posts (content, title, user_id, tags, status, publish_at)
+	   VALUES ($1, $2, $3, $4, $5, CASE WHEN $5 = 'published' THEN NOW() ELSE $6::timestamptz END)
+	   RETURNING id, publish_at, created_at, updated_at, version
 	   `
 
-	err := p.db.QueryRowContext(
-		ctx,
-		query,
-		post.Content,
-		post.Title,
-		post.UserID,
-		pq.Array(post.Tags),
-	).Scan(
-		&post.ID,
-		&post.CreatedAt,
-		&post.UpdatedAt,
-	)
+	if post.Status == "" {
+		post.Status = PostPublished
+	}
+	err := withTx(p.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			post.Content,
+			post.Title,
+			post.UserID,
+			pq.Array(post.Tags),
+			post.Status,
+			post.PublishAt,
+		).Scan(
+			&post.ID,
+			&post.PublishAt,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.Version,
+		)
+		if err != nil {
+			return err
+		}
+
+		return writeRevision(ctx, tx, post, post.UserID)
+	})
 	if err != nil {
 		return err
 	}
@@ -71,6 +97,8 @@ func (p *PostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
             title,
             user_id,
             tags,
+            status,
+            publish_at,
             created_at,
             updated_at,
 			version
@@ -84,6 +112,8 @@ func (p *PostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
 		&post.Title,
 		&post.UserID,
 		pq.Array(&post.Tags),
+		&post.Status,
+		&post.PublishAt,
 		&post.CreatedAt,
 		&post.UpdatedAt,
 		&post.Version,
@@ -126,7 +156,9 @@ func (p *PostStore) DeleteByID(ctx context.Context, postID int64) error {
 	return nil
 }
 
-func (p *PostStore) UpdateByID(ctx context.Context, post *Post) error {
+// UpdateByID saves the post and keeps the new version in post_revisions.
+// editorID is the user who made the change, the author or a moderator
+func (p *PostStore) UpdateByID(ctx context.Context, post *Post, editorID int64) error {
 	if p.db == nil {
 		return errors.New("nil db in PostStore")
 	}
@@ -134,20 +166,39 @@ func (p *PostStore) UpdateByID(ctx context.Context, post *Post) error {
 	//For example two req readed post with same id 10 and version 1. First will write because version = $4(1)
 	//Then first chenge version to 2. So when second try to execute SQL it just not find the row with id 10 and version 1
 	//because version was updated to 2 by first req. Error "sql.ErrNoRows" will be returned from DB
+	//Draft or scheduled post which becomes published gets publish_at of this moment.
+	//PublishDue does not change the version, so the post published by the scheduler
+	//meanwhile is saved, but stays published with its publish_at.
+	//updated_at is the time of the last edit, the first revision of old posts is taken from it
 	const query = `
        UPDATE posts
-       SET title = $1, content = $2, version = version + 1
+       SET title = $1, content = $2,
+           status = CASE WHEN status = 'published' THEN status ELSE $5 END,
+           publish_at = CASE
+               WHEN status = 'published' THEN publish_at
+               WHEN $5 = 'published' THEN NOW()
+               ELSE $6::timestamptz END,
+           version = version + 1, updated_at = NOW()
 	   WHERE id = $3 AND version = $4
-	   RETURNING version
+	   RETURNING version, status, publish_at, updated_at
     `
 
-	err := p.db.QueryRowContext(
-		ctx, query,
-		post.Title,
-		post.Content,
-		post.ID,
-		post.Version,
-	).Scan(&post.Version)
+	err := withTx(p.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx, query,
+			post.Title,
+			post.Content,
+			post.ID,
+			post.Version,
+			post.Status,
+			post.PublishAt,
+		).Scan(&post.Version, &post.Status, &post.PublishAt, &post.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return writeRevision(ctx, tx, post, editorID)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -159,6 +210,9 @@ func (p *PostStore) UpdateByID(ctx context.Context, post *Post) error {
 	return nil
 }
 
+// GetUserFeed returns posts of followed users and own posts. Posts of private
+// users are included only after follow request was approved, because the
+// followers row is created on approval
 func (p *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
 	if p.db == nil {
 		return nil, errors.New("nil db in PostStore")
@@ -167,6 +221,7 @@ func (p *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedF
 	query := `
 		SELECT
 			p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags,
+			p.status, p.publish_at,
 			u.username,
 			COUNT(c.id) AS comments_count
 		FROM posts p
@@ -175,10 +230,18 @@ func (p *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedF
 		JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
 		WHERE
 			f.user_id = $1 AND
+			p.status = 'published' AND
+			u.deleted_at IS NULL AND
+			(u.suspended_until IS NULL OR u.suspended_until < NOW()) AND
 			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
-			(p.tags @> $5 OR $5 IS NULL)
+			(p.tags @> $5 OR $5 IS NULL) AND
+			NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.user_id = $1 AND b.blocked_id = p.user_id) OR (b.user_id = p.user_id AND b.blocked_id = $1)
+			) AND
+			NOT EXISTS (SELECT 1 FROM user_mutes m WHERE m.user_id = $1 AND m.muted_id = p.user_id)
 		GROUP BY p.id, u.username
-		ORDER BY p.created_at ` + fq.Sort + `
+		ORDER BY p.publish_at ` + fq.Sort + `
 		LIMIT $2 OFFSET $3
 	`
 
@@ -201,6 +264,8 @@ func (p *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedF
 			&p.CreatedAt,
 			&p.Version,
 			pq.Array(&p.Tags),
+			&p.Status,
+			&p.PublishAt,
 			&p.User.Username,
 			&p.CommentsCount,
 		)
@@ -213,3 +278,150 @@ func (p *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedF
 
 	return feed, nil
 }
+
+// GetByUserID returns posts of one user for the profile page. Visibility of
+// the author is checked by the caller with CanSeePosts
+func (p *PostStore) GetByUserID(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	if p.db == nil {
+		return nil, errors.New("nil db in PostStore")
+	}
+
+	query := `
+		SELECT
+			p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags,
+			p.status, p.publish_at,
+			u.username,
+			COUNT(c.id) AS comments_count
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		LEFT JOIN comments c ON c.post_id = p.id
+		WHERE
+			p.user_id = $1 AND
+			p.status = 'published' AND
+			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
+			(p.tags @> $5 OR $5 IS NULL) AND
+			(NULLIF($6, '') IS NULL OR p.publish_at >= NULLIF($6, '')::timestamptz) AND
+			(NULLIF($7, '') IS NULL OR p.publish_at <= NULLIF($7, '')::timestamptz)
+		GROUP BY p.id, u.username
+		ORDER BY p.publish_at ` + fq.Sort + `
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := p.db.QueryContext(
+		ctx, query, userID, fq.Limit,
+		fq.Offset, fq.Search, pq.Array(fq.Tags), fq.Since, fq.Until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []PostWithMetadata{}
+	for rows.Next() {
+		var p PostWithMetadata
+		err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.Title,
+			&p.Content,
+			&p.CreatedAt,
+			&p.Version,
+			pq.Array(&p.Tags),
+			&p.Status,
+			&p.PublishAt,
+			&p.User.Username,
+			&p.CommentsCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, p)
+	}
+
+	return posts, rows.Err()
+}
+
+// GetDrafts returns draft and scheduled posts of the author. Scheduled posts
+// go first ordered by publish time, then drafts from the newest
+func (p *PostStore) GetDrafts(ctx context.Context, userID int64, q PaginatedQuery) ([]Post, error) {
+	if p.db == nil {
+		return nil, errors.New("nil db in PostStore")
+	}
+
+	const query = `
+		SELECT id, user_id, title, content, tags, status, publish_at, created_at, updated_at, version
+		FROM posts
+		WHERE user_id = $1 AND status <> 'published'
+		ORDER BY publish_at ASC NULLS LAST, created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, userID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var post Post
+		err := rows.Scan(
+			&post.ID,
+			&post.UserID,
+			&post.Title,
+			&post.Content,
+			pq.Array(&post.Tags),
+			&post.Status,
+			&post.PublishAt,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// PublishDue publishes scheduled posts whose time has come. Rows are locked
+// with SKIP LOCKED so several API instances never publish the same post twice.
+// Title and content are not changed, so version stays the same and no revision
+// is written, edit of the author made at the same time is not rejected.
+// Returns ids of authors whose posts were published
+func (p *PostStore) PublishDue(ctx context.Context, limit int) ([]int64, error) {
+	if p.db == nil {
+		return nil, errors.New("nil db in PostStore")
+	}
+
+	const query = `
+		UPDATE posts SET status = 'published'
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE status = 'scheduled' AND publish_at <= NOW()
+			ORDER BY publish_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING user_id
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var authors []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		authors = append(authors, id)
+	}
+
+	return authors, rows.Err()
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrTokenReused = errors.New("refresh token was already used")
+
+type RefreshToken struct {
+	UserID    int64     `json:"user_id"`
+	FamilyID  string    `json:"family_id"`
+	Expiry    time.Time `json:"expiry"`
+	CreatedAt string    `json:"created_at"`
+}
+
+type RefreshTokenStore struct {
+	db *sql.DB
+}
+
+// Create stores a hash of the plain token. All tokens that were rotated from
+// the same login share FamilyID so they can be revoked together
+func (s *RefreshTokenStore) Create(ctx context.Context, token string, rt *RefreshToken) error {
+	if s.db == nil {
+		return errors.New("nil db in RefreshTokenStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return createRefreshToken(ctx, tx, token, rt)
+	})
+}
+
+// Rotate revokes the presented token and issues newToken in the same family.
+// If the presented token was already revoked someone is replaying it(token leak),
+// so the whole family and its session are revoked and ErrTokenReused is returned
+// together with the token, so the caller knows whose session it was
+func (s *RefreshTokenStore) Rotate(
+	ctx context.Context,
+	token, newToken string,
+	exp time.Duration) (*RefreshToken, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in RefreshTokenStore")
+	}
+
+	var (
+		rt     RefreshToken
+		reused bool
+	)
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		const query = `
+			SELECT user_id, family_id, expiry, revoked_at
+			FROM refresh_tokens
+			WHERE token = $1
+			FOR UPDATE
+		`
+		var revokedAt sql.NullTime
+		err := tx.QueryRowContext(ctx, query, hashToken(token)).Scan(
+			&rt.UserID,
+			&rt.FamilyID,
+			&rt.Expiry,
+			&revokedAt,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if revokedAt.Valid {
+			//Revocation must be commited, so we do not return an error here
+			reused = true
+			return revokeSession(ctx, tx, rt.FamilyID)
+		}
+
+		if rt.Expiry.Before(time.Now()) {
+			return ErrNotFound
+		}
+
+		const revokeQuery = `UPDATE refresh_tokens SET revoked_at = NOW() WHERE token = $1`
+		if _, err := tx.ExecContext(ctx, revokeQuery, hashToken(token)); err != nil {
+			return err
+		}
+
+		rt.Expiry = time.Now().Add(exp)
+		return createRefreshToken(ctx, tx, newToken, &rt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return &rt, ErrTokenReused
+	}
+
+	return &rt, nil
+}
+
+// RevokeFamily revokes the token and every token rotated from the same login
+// and deletes the session of the login. Returns ID of the token owner
+func (s *RefreshTokenStore) RevokeFamily(ctx context.Context, token string) (int64, error) {
+	if s.db == nil {
+		return 0, errors.New("nil db in RefreshTokenStore")
+	}
+
+	var userID int64
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		const query = `
+			SELECT user_id, family_id FROM refresh_tokens
+			WHERE token = $1 AND revoked_at IS NULL
+		`
+		var familyID string
+		err := tx.QueryRowContext(ctx, query, hashToken(token)).Scan(&userID, &familyID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		return revokeSession(ctx, tx, familyID)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func createRefreshToken(ctx context.Context, tx *sql.Tx, token string, rt *RefreshToken) error {
+	const query = `
+		INSERT INTO refresh_tokens (token, user_id, family_id, expiry)
+		VALUES ($1, $2, $3, $4) RETURNING created_at
+	`
+
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		hashToken(token),
+		rt.UserID,
+		rt.FamilyID,
+		rt.Expiry,
+	).Scan(&rt.CreatedAt)
+}
+
+// revokeSession revokes refresh tokens of the login and deletes its session
+func revokeSession(ctx context.Context, tx *sql.Tx, familyID string) error {
+	const query = `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, familyID); err != nil {
+		return err
+	}
+
+	const sessionQuery = `DELETE FROM sessions WHERE id = $1`
+	_, err := tx.ExecContext(ctx, sessionQuery, familyID)
+	return err
+}
+
+// revokeUserSessions logs out the user everywhere
+func revokeUserSessions(ctx context.Context, tx *sql.Tx, userID int64) error {
+	const query = `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
+
+	const sessionQuery = `DELETE FROM sessions WHERE user_id = $1`
+	_, err := tx.ExecContext(ctx, sessionQuery, userID)
+	return err
+}
@@ -3,32 +3,209 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Permissions allow actions on content of other users and admin actions.
+// Own posts and comments are managed without permissions
+const (
+	PermPostsUpdateAny    = "posts.update.any"
+	PermPostsDeleteAny    = "posts.delete.any"
+	PermCommentsDeleteAny = "comments.delete.any"
+	PermUsersSuspend      = "users.suspend"
+	PermUsersImpersonate  = "users.impersonate"
+	PermRolesManage       = "roles.manage"
+	PermAuditRead         = "audit.read"
 )
 
+// Permissions are all permissions which can be granted to a role
+var Permissions = []string{
+	PermPostsUpdateAny,
+	PermPostsDeleteAny,
+	PermCommentsDeleteAny,
+	PermUsersSuspend,
+	PermUsersImpersonate,
+	PermRolesManage,
+	PermAuditRead,
+}
+
+// Role has a set of permissions. Level orders roles, user can not manage
+// users with the same or higher level
 type Role struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Level       int    `json:"level"`
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Level       int      `json:"level"`
+	Permissions []string `json:"permissions"`
 }
 
 type RoleStore struct {
 	db *sql.DB
 }
 
+const roleColumns = `
+	r.id, r.name, COALESCE(r.description, ''), r.level,
+	ARRAY(SELECT permission FROM role_permissions WHERE role_id = r.id ORDER BY permission)
+`
+
 func (s *RoleStore) GetByName(ctx context.Context, slug string) (*Role, error) {
-	query := `SELECT id, name, description, level FROM roles WHERE name = $1`
+	query := `SELECT ` + roleColumns + ` FROM roles r WHERE r.name = $1`
+
+	return s.get(ctx, query, slug)
+}
+
+func (s *RoleStore) GetByID(ctx context.Context, roleID int64) (*Role, error) {
+	query := `SELECT ` + roleColumns + ` FROM roles r WHERE r.id = $1`
+
+	return s.get(ctx, query, roleID)
+}
+
+func (s *RoleStore) get(ctx context.Context, query string, arg any) (*Role, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in RoleStore")
+	}
 
 	role := &Role{}
-	err := s.db.QueryRowContext(ctx, query, slug).
+	err := s.db.QueryRowContext(ctx, query, arg).
 		Scan(
 			&role.ID,
 			&role.Name,
 			&role.Description,
-			&role.Level)
+			&role.Level,
+			pq.Array(&role.Permissions))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
 	return role, nil
 }
+
+func (s *RoleStore) GetAll(ctx context.Context) ([]Role, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in RoleStore")
+	}
+	query := `SELECT ` + roleColumns + ` FROM roles r ORDER BY r.level, r.name`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var role Role
+		err := rows.Scan(
+			&role.ID,
+			&role.Name,
+			&role.Description,
+			&role.Level,
+			pq.Array(&role.Permissions))
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// Create adds a custom role with permissions. ErrConflict is returned if the name is taken
+func (s *RoleStore) Create(ctx context.Context, role *Role, actorID int64) error {
+	if s.db == nil {
+		return errors.New("nil db in RoleStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO roles (name, level, description) VALUES ($1, $2, $3)
+			RETURNING id
+		`
+		err := tx.QueryRowContext(ctx, query, role.Name, role.Level, role.Description).Scan(&role.ID)
+		if err != nil {
+			if _, ok := pgError(err, pgUniqueViolation); ok {
+				return ErrConflict
+			}
+			return err
+		}
+
+		if err := insertPermissions(ctx, tx, role.ID, role.Permissions); err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, actorID, AuditRoleCreated, nil, role)
+	})
+}
+
+// SetPermissions replaces permissions of the role
+func (s *RoleStore) SetPermissions(ctx context.Context, role *Role, actorID int64) error {
+	if s.db == nil {
+		return errors.New("nil db in RoleStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `DELETE FROM role_permissions WHERE role_id = $1`
+		if _, err := tx.ExecContext(ctx, query, role.ID); err != nil {
+			return err
+		}
+
+		if err := insertPermissions(ctx, tx, role.ID, role.Permissions); err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, actorID, AuditRolePermissions, nil, role)
+	})
+}
+
+func insertPermissions(ctx context.Context, tx *sql.Tx, roleID int64, permissions []string) error {
+	const query = `
+		INSERT INTO role_permissions (role_id, permission)
+		SELECT $1, unnest($2::varchar[])
+		ON CONFLICT DO NOTHING
+	`
+	_, err := tx.ExecContext(ctx, query, roleID, pq.Array(permissions))
+	return err
+}
+
+// Assign gives the role to the user. ErrNotFound is returned if the user or the role does not exist
+func (s *RoleStore) Assign(ctx context.Context, userID int64, roleName string, actorID int64) error {
+	if s.db == nil {
+		return errors.New("nil db in RoleStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var previous string
+		query := `
+			SELECT r.name FROM users u
+			JOIN roles r ON r.id = u.role_id
+			WHERE u.id = $1 AND u.deleted_at IS NULL
+			FOR UPDATE OF u
+		`
+		if err := tx.QueryRowContext(ctx, query, userID).Scan(&previous); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		query = `UPDATE users SET role_id = (SELECT id FROM roles WHERE name = $2) WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, userID, roleName); err != nil {
+			//role_id is NOT NULL, so unknown role fails the update
+			if _, ok := pgError(err, pgNotNullViolation); ok {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		details := struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		}{previous, roleName}
+		return writeAudit(ctx, tx, actorID, AuditRoleAssigned, &userID, details)
+	})
+}
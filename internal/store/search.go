@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// UserSummary is the user in search results
+type UserSummary struct {
+	ID             int64  `json:"id"`
+	Username       string `json:"username"`
+	DisplayName    string `json:"display_name"`
+	AvatarURL      string `json:"avatar_url"`
+	FollowersCount int64  `json:"followers_count"`
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search returns users matching q.Query, exact matches of username or display
+// name go first. Full search uses trigram similarity, so typos are tolerated,
+// autocomplete uses only prefix match which is cheap enough for every keystroke.
+// Users who blocked viewerID or were blocked by them are excluded
+func (u *UserStore) Search(ctx context.Context, viewerID int64, q UserSearchQuery) ([]UserSummary, error) {
+	if u.db == nil {
+		return nil, errors.New("nil db in UserStore")
+	}
+
+	const visible = `
+		u.is_active = true AND u.deleted_at IS NULL AND
+		(u.suspended_until IS NULL OR u.suspended_until < NOW()) AND
+		NOT EXISTS (
+			SELECT 1 FROM user_blocks b
+			WHERE (b.user_id = $1 AND b.blocked_id = u.id) OR (b.user_id = u.id AND b.blocked_id = $1)
+		)
+	`
+
+	query := `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, u.followers_count
+		FROM users u
+		WHERE ` + visible + ` AND (
+			lower(u.username) LIKE $3 || '%' OR lower(u.display_name) LIKE $3 || '%'
+		)
+		ORDER BY
+			lower(u.username) = lower($2) DESC,
+			lower(u.display_name) = lower($2) DESC,
+			length(u.username),
+			u.followers_count DESC,
+			u.id
+		LIMIT $4
+	`
+	args := []any{viewerID, q.Query, likeEscaper.Replace(strings.ToLower(q.Query)), q.Limit}
+
+	if !q.Autocomplete {
+		query = `
+			SELECT u.id, u.username, u.display_name, u.avatar_url, u.followers_count
+			FROM users u
+			WHERE ` + visible + ` AND (
+				u.username % $2 OR u.display_name % $2 OR
+				lower(u.username) LIKE $3 || '%' OR lower(u.display_name) LIKE $3 || '%'
+			)
+			ORDER BY
+				lower(u.username) = lower($2) DESC,
+				lower(u.display_name) = lower($2) DESC,
+				GREATEST(similarity(u.username, $2), similarity(u.display_name, $2)) DESC,
+				u.followers_count DESC,
+				u.id
+			LIMIT $4 OFFSET $5
+		`
+		args = append(args, q.Offset)
+	}
+
+	rows, err := u.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []UserSummary{}
+	for rows.Next() {
+		var s UserSummary
+		err := rows.Scan(
+			&s.ID,
+			&s.Username,
+			&s.DisplayName,
+			&s.AvatarURL,
+			&s.FollowersCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, s)
+	}
+
+	return users, rows.Err()
+}
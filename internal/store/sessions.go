@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Session is a single login of the user. ID is the family of refresh tokens
+// of the login and is put into access tokens as "jti" claim
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int64     `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Expiry     time.Time `json:"expires_at"`
+	CreatedAt  string    `json:"created_at"`
+	LastSeenAt string    `json:"last_seen_at"`
+}
+
+type SessionStore struct {
+	db *sql.DB
+}
+
+// Create stores the session together with its first refresh token
+func (s *SessionStore) Create(ctx context.Context, session *Session, refreshToken string) error {
+	if s.db == nil {
+		return errors.New("nil db in SessionStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		const query = `
+			INSERT INTO sessions (id, user_id, user_agent, ip, expiry)
+			VALUES ($1, $2, $3, $4, $5) RETURNING created_at, last_seen_at
+		`
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			session.ID,
+			session.UserID,
+			session.UserAgent,
+			session.IP,
+			session.Expiry,
+		).Scan(
+			&session.CreatedAt,
+			&session.LastSeenAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		return createRefreshToken(ctx, tx, refreshToken, &RefreshToken{
+			UserID:   session.UserID,
+			FamilyID: session.ID,
+			Expiry:   session.Expiry,
+		})
+	})
+}
+
+// GetByUserID returns not expired sessions, the last seen first
+func (s *SessionStore) GetByUserID(ctx context.Context, userID int64) ([]Session, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in SessionStore")
+	}
+
+	const query = `
+		SELECT id, user_id, user_agent, ip, expiry, created_at, last_seen_at
+		FROM sessions
+		WHERE user_id = $1 AND expiry > NOW()
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IP,
+			&session.Expiry,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// Touch updates the device, expiry and last seen time of the session
+// when its refresh token is rotated
+func (s *SessionStore) Touch(ctx context.Context, session *Session) error {
+	if s.db == nil {
+		return errors.New("nil db in SessionStore")
+	}
+
+	const query = `
+		UPDATE sessions SET user_agent = $1, ip = $2, expiry = $3, last_seen_at = NOW()
+		WHERE id = $4 AND user_id = $5
+		RETURNING created_at, last_seen_at
+	`
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		session.UserAgent,
+		session.IP,
+		session.Expiry,
+		session.ID,
+		session.UserID,
+	).Scan(
+		&session.CreatedAt,
+		&session.LastSeenAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoggedInSince reports whether the session of the user was created by a login
+// after since. Refresh of the tokens keeps the session, so it is time of the last login
+func (s *SessionStore) LoggedInSince(
+	ctx context.Context, userID int64, sessionID string, since time.Time) (bool, error) {
+	if s.db == nil {
+		return false, errors.New("nil db in SessionStore")
+	}
+
+	const query = `
+		SELECT EXISTS(
+			SELECT 1 FROM sessions
+			WHERE id::text = $1 AND user_id = $2 AND created_at > $3
+		)
+	`
+
+	var recent bool
+	if err := s.db.QueryRowContext(ctx, query, sessionID, userID, since).Scan(&recent); err != nil {
+		return false, err
+	}
+
+	return recent, nil
+}
+
+// Delete logs out the session of the user and revokes its refresh tokens
+func (s *SessionStore) Delete(ctx context.Context, userID int64, sessionID string) error {
+	if s.db == nil {
+		return errors.New("nil db in SessionStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		const query = `DELETE FROM sessions WHERE id = $1 AND user_id = $2`
+
+		res, err := tx.ExecContext(ctx, query, sessionID, userID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return revokeSession(ctx, tx, sessionID)
+	})
+}
+
+// DeleteOthers logs out every session of the user except keepID
+func (s *SessionStore) DeleteOthers(ctx context.Context, userID int64, keepID string) error {
+	if s.db == nil {
+		return errors.New("nil db in SessionStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		const query = `DELETE FROM sessions WHERE user_id = $1 AND id::text <> $2`
+		if _, err := tx.ExecContext(ctx, query, userID, keepID); err != nil {
+			return err
+		}
+
+		const revokeQuery = `
+			UPDATE refresh_tokens SET revoked_at = NOW()
+			WHERE user_id = $1 AND family_id::text <> $2 AND revoked_at IS NULL
+		`
+		_, err := tx.ExecContext(ctx, revokeQuery, userID, keepID)
+		return err
+	})
+}
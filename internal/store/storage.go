@@ -2,9 +2,13 @@ package store
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 var (
@@ -14,54 +18,190 @@ var (
 	ErrDuplicateUsername = errors.New("username already exists")
 )
 
-//go:generate mockgen -source=./storage.go -destination=../../cmd/api/mock/store/Mock_Storage.go -package=mock_storage Posts,Users,Comments,Followers,Roles
+// Postgres error codes which are mapped to store errors
+const (
+	pgUniqueViolation     = "23505"
+	pgNotNullViolation    = "23502"
+	pgForeignKeyViolation = "23503"
+)
+
+// pgError returns the postgres error if err is one with the code.
+// The pool uses pgx driver, so errors are *pgconn.PgError
+func pgError(err error, code string) (*pgconn.PgError, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == code {
+		return pgErr, true
+	}
+	return nil, false
+}
+
+//go:generate mockgen -source=./storage.go -destination=../../cmd/api/mock/store/Mock_Storage.go -package=mock_storage Posts,Users,Comments,Followers,Roles,RefreshTokens,Sessions,TwoFactor,PersonalTokens,Identities,Blocks,DataExports,Suspensions,Audit,PostRevisions
 
 type Posts interface {
 	Create(context.Context, *Post) error
 	GetByID(context.Context, int64) (*Post, error)
 	DeleteByID(context.Context, int64) error
-	UpdateByID(context.Context, *Post) error
+	UpdateByID(context.Context, *Post, int64) error
 	GetUserFeed(context.Context, int64, PaginatedFeedQuery) ([]PostWithMetadata, error)
+	GetByUserID(context.Context, int64, PaginatedFeedQuery) ([]PostWithMetadata, error)
+	GetDrafts(context.Context, int64, PaginatedQuery) ([]Post, error)
+	PublishDue(context.Context, int) ([]int64, error)
 }
 
 type Users interface {
 	Create(context.Context, *sql.Tx, *User) error
 	GetByID(context.Context, int64) (*User, error)
 	GetByEmail(context.Context, string) (*User, error)
+	UpdateProfile(context.Context, *User) error
 	CreateAndInvite(context.Context, *User, string, time.Duration) error
 	Activate(context.Context, string) error
+	RenewInvitation(context.Context, string, string, time.Duration) (*User, error)
+	DeleteExpiredInvitations(context.Context) (int64, error)
+	DeleteNotActivated(context.Context, time.Duration) (int64, error)
+	CreatePasswordReset(context.Context, int64, string, time.Duration) error
+	ResetPassword(context.Context, string, *User) error
+	ChangePassword(context.Context, *User) error
+	CreateEmailChange(context.Context, int64, string, string, time.Duration) error
+	ConfirmEmailChange(context.Context, string) (int64, error)
 	Delete(context.Context, int64) error
+	ScheduleDeletion(context.Context, int64) error
+	CancelDeletion(context.Context, int64) error
+	PurgeDeleted(context.Context, time.Duration, bool) (int64, error)
+	Search(context.Context, int64, UserSearchQuery) ([]UserSummary, error)
 }
 
 type Comments interface {
 	Create(context.Context, *Comment) error
 	GetByPostID(context.Context, int64) ([]Comment, error)
+	GetByID(context.Context, int64) (*Comment, error)
+	Delete(context.Context, int64) error
 }
 
 type Followers interface {
-	Follow(context.Context, int64, int64) error
+	Follow(context.Context, int64, int64) (bool, error)
 	Unfollow(context.Context, int64, int64) error
+	GetRequests(context.Context, int64, PaginatedQuery) ([]FollowRequest, error)
+	ApproveRequest(context.Context, int64, int64) error
+	RejectRequest(context.Context, int64, int64) error
+	CanSeePosts(context.Context, int64, int64) (bool, error)
+	GetSuggestions(context.Context, int64, int) ([]Suggestion, error)
+	GetFollowers(context.Context, int64, PaginatedQuery) ([]FollowEntry, error)
+	GetFollowing(context.Context, int64, PaginatedQuery) ([]FollowEntry, error)
+	GetRelationship(context.Context, int64, int64) (*Relationship, error)
 }
 
 type Roles interface {
 	GetByName(context.Context, string) (*Role, error)
+	GetByID(context.Context, int64) (*Role, error)
+	GetAll(context.Context) ([]Role, error)
+	Create(context.Context, *Role, int64) error
+	SetPermissions(context.Context, *Role, int64) error
+	Assign(context.Context, int64, string, int64) error
+}
+
+type RefreshTokens interface {
+	Create(context.Context, string, *RefreshToken) error
+	Rotate(context.Context, string, string, time.Duration) (*RefreshToken, error)
+	RevokeFamily(context.Context, string) (int64, error)
+}
+
+type Sessions interface {
+	Create(context.Context, *Session, string) error
+	GetByUserID(context.Context, int64) ([]Session, error)
+	Touch(context.Context, *Session) error
+	LoggedInSince(context.Context, int64, string, time.Time) (bool, error)
+	Delete(context.Context, int64, string) error
+	DeleteOthers(context.Context, int64, string) error
+}
+
+type TwoFactor interface {
+	Enroll(context.Context, int64, string) error
+	Get(context.Context, int64) (*TOTP, error)
+	Confirm(context.Context, int64, int64, []string) error
+	UseCode(context.Context, int64, int64) error
+	UseRecoveryCode(context.Context, int64, string) error
+	Disable(context.Context, int64) error
+}
+
+type PersonalTokens interface {
+	Create(context.Context, string, *PersonalToken) error
+	GetByUserID(context.Context, int64) ([]PersonalToken, error)
+	Authenticate(context.Context, string) (*PersonalToken, error)
+	Delete(context.Context, int64, int64) error
+}
+
+type Identities interface {
+	FindOrCreateUser(context.Context, *Identity, *User, bool) error
+}
+
+type Blocks interface {
+	Block(context.Context, int64, int64) error
+	Unblock(context.Context, int64, int64) error
+	Mute(context.Context, int64, int64) error
+	Unmute(context.Context, int64, int64) error
+	IsBlocked(context.Context, int64, int64) (bool, error)
+}
+
+type DataExports interface {
+	Create(context.Context, *DataExport, time.Duration) error
+	ClaimPending(context.Context) (*DataExport, error)
+	Finish(context.Context, *DataExport, string, time.Duration) error
+	GetByToken(context.Context, string) (*DataExport, error)
+	Collect(context.Context, int64) (*UserData, error)
+}
+
+type Suspensions interface {
+	Suspend(context.Context, *Suspension) error
+	Lift(context.Context, int64, int64) error
+	GetActive(context.Context, int64) (*Suspension, error)
+	GetHistory(context.Context, int64) ([]Suspension, error)
+}
+
+type Audit interface {
+	Create(context.Context, *AuditEntry) error
+	GetAll(context.Context, PaginatedQuery) ([]AuditEntry, error)
+}
+
+type PostRevisions interface {
+	GetByPostID(context.Context, int64, PaginatedQuery) ([]PostRevision, error)
+	GetByVersion(context.Context, int64, int) (*PostRevision, error)
 }
 
 type Storage struct {
-	Posts     Posts
-	Users     Users
-	Comments  Comments
-	Followers Followers
-	Roles     Roles
+	Posts          Posts
+	Users          Users
+	Comments       Comments
+	Followers      Followers
+	Roles          Roles
+	RefreshTokens  RefreshTokens
+	Sessions       Sessions
+	TwoFactor      TwoFactor
+	PersonalTokens PersonalTokens
+	Identities     Identities
+	Blocks         Blocks
+	DataExports    DataExports
+	Suspensions    Suspensions
+	Audit          Audit
+	PostRevisions  PostRevisions
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Posts:     &PostStore{db: db},
-		Users:     &UserStore{db: db},
-		Comments:  &CommentStore{db: db},
-		Followers: &FollowersStore{db: db},
-		Roles:     &RoleStore{db: db},
+		Posts:          &PostStore{db: db},
+		Users:          &UserStore{db: db},
+		Comments:       &CommentStore{db: db},
+		Followers:      &FollowersStore{db: db},
+		Roles:          &RoleStore{db: db},
+		RefreshTokens:  &RefreshTokenStore{db: db},
+		Sessions:       &SessionStore{db: db},
+		TwoFactor:      &TwoFactorStore{db: db},
+		PersonalTokens: &PersonalTokenStore{db: db},
+		Identities:     &IdentityStore{db: db},
+		Blocks:         &BlockStore{db: db},
+		DataExports:    &DataExportStore{db: db},
+		Suspensions:    &SuspensionStore{db: db},
+		Audit:          &AuditStore{db: db},
+		PostRevisions:  &PostRevisionStore{db: db},
 	}
 }
 
@@ -82,3 +222,10 @@ func withTx(
 
 	return tx.Commit()
 }
+
+// Tokens that are sent to users are stored only as a hash,
+// so a DB leak does not expose them
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,23 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestPgError(t *testing.T) {
+	unique := &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "users_email_key"}
+
+	if pgErr, ok := pgError(fmt.Errorf("insert: %w", unique), pgUniqueViolation); !ok || pgErr.ConstraintName != "users_email_key" {
+		t.Errorf("expected wrapped unique violation to match got %v %v", pgErr, ok)
+	}
+	if _, ok := pgError(unique, pgNotNullViolation); ok {
+		t.Error("error with other code should not match")
+	}
+	if _, ok := pgError(errors.New("23505"), pgUniqueViolation); ok {
+		t.Error("not postgres error should not match")
+	}
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Suspension forbids the user to use the API. ExpiresAt is nil for permanent ban
+type Suspension struct {
+	ID        int64   `json:"id"`
+	UserID    int64   `json:"user_id"`
+	AdminID   *int64  `json:"admin_id"`
+	Reason    string  `json:"reason"`
+	ExpiresAt *string `json:"expires_at"`
+	CreatedAt string  `json:"created_at"`
+	LiftedAt  *string `json:"lifted_at,omitempty"`
+	LiftedBy  *int64  `json:"lifted_by,omitempty"`
+}
+
+// Active reports if the suspension is still in force. Cached user keeps the
+// suspension after it expired, so expiry is checked here and not only in DB
+func (s *Suspension) Active() bool {
+	if s == nil || s.LiftedAt != nil {
+		return false
+	}
+	if s.ExpiresAt == nil {
+		return true
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, *s.ExpiresAt)
+	if err != nil {
+		//Unknown expiry is safer to treat as active
+		return true
+	}
+	return time.Now().Before(expiresAt)
+}
+
+type SuspensionStore struct {
+	db *sql.DB
+}
+
+// Suspend replaces the active suspension of the user with a new one.
+// All sessions are revoked, so refresh tokens stop working too
+func (s *SuspensionStore) Suspend(ctx context.Context, suspension *Suspension) error {
+	if s.db == nil {
+		return errors.New("nil db in SuspensionStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			UPDATE users SET suspended_until = COALESCE($2::timestamptz, 'infinity')
+			WHERE id = $1 AND is_active = true AND deleted_at IS NULL
+		`
+		res, err := tx.ExecContext(ctx, query, suspension.UserID, suspension.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		query = `
+			UPDATE user_suspensions SET lifted_at = NOW(), lifted_by = $2
+			WHERE user_id = $1 AND lifted_at IS NULL
+		`
+		if _, err := tx.ExecContext(ctx, query, suspension.UserID, suspension.AdminID); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO user_suspensions (user_id, admin_id, reason, expires_at)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at
+		`
+		err = tx.QueryRowContext(
+			ctx, query, suspension.UserID, suspension.AdminID, suspension.Reason, suspension.ExpiresAt,
+		).Scan(&suspension.ID, &suspension.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		return revokeUserSessions(ctx, tx, suspension.UserID)
+	})
+}
+
+// Lift ends the active suspension before it expires.
+// ErrNotFound is returned if the user is not suspended
+func (s *SuspensionStore) Lift(ctx context.Context, userID int64, adminID int64) error {
+	if s.db == nil {
+		return errors.New("nil db in SuspensionStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			UPDATE user_suspensions SET lifted_at = NOW(), lifted_by = $2
+			WHERE user_id = $1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		`
+		res, err := tx.ExecContext(ctx, query, userID, adminID)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		query = `UPDATE users SET suspended_until = NULL WHERE id = $1`
+		_, err = tx.ExecContext(ctx, query, userID)
+		return err
+	})
+}
+
+// GetActive returns the suspension in force or ErrNotFound
+func (s *SuspensionStore) GetActive(ctx context.Context, userID int64) (*Suspension, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in SuspensionStore")
+	}
+	const query = `
+		SELECT id, user_id, admin_id, reason, expires_at, created_at
+		FROM user_suspensions
+		WHERE user_id = $1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var suspension Suspension
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(
+		&suspension.ID,
+		&suspension.UserID,
+		&suspension.AdminID,
+		&suspension.Reason,
+		&suspension.ExpiresAt,
+		&suspension.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &suspension, nil
+}
+
+// GetHistory returns all suspensions of the user, the newest first
+func (s *SuspensionStore) GetHistory(ctx context.Context, userID int64) ([]Suspension, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in SuspensionStore")
+	}
+	const query = `
+		SELECT id, user_id, admin_id, reason, expires_at, created_at, lifted_at, lifted_by
+		FROM user_suspensions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suspensions := []Suspension{}
+	for rows.Next() {
+		var suspension Suspension
+		err := rows.Scan(
+			&suspension.ID,
+			&suspension.UserID,
+			&suspension.AdminID,
+			&suspension.Reason,
+			&suspension.ExpiresAt,
+			&suspension.CreatedAt,
+			&suspension.LiftedAt,
+			&suspension.LiftedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		suspensions = append(suspensions, suspension)
+	}
+
+	return suspensions, rows.Err()
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type TOTP struct {
+	UserID       int64  `json:"user_id"`
+	Secret       string `json:"-"`
+	Confirmed    bool   `json:"confirmed"`
+	LastUsedStep int64  `json:"-"`
+}
+
+type TwoFactorStore struct {
+	db *sql.DB
+}
+
+// Enroll saves a new unconfirmed secret. Enrollment can be restarted
+// until it is confirmed, after that ErrConflict is returned
+func (s *TwoFactorStore) Enroll(ctx context.Context, userID int64, secret string) error {
+	if s.db == nil {
+		return errors.New("nil db in TwoFactorStore")
+	}
+
+	const query = `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL
+	`
+
+	res, err := s.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrConflict
+	}
+
+	return nil
+}
+
+func (s *TwoFactorStore) Get(ctx context.Context, userID int64) (*TOTP, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db in TwoFactorStore")
+	}
+
+	const query = `
+		SELECT user_id, secret, confirmed_at IS NOT NULL, last_used_step
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	var totp TOTP
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(
+		&totp.UserID,
+		&totp.Secret,
+		&totp.Confirmed,
+		&totp.LastUsedStep,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &totp, nil
+}
+
+// Confirm enables 2FA and replaces recovery codes of the user
+func (s *TwoFactorStore) Confirm(ctx context.Context, userID int64, step int64, recoveryCodes []string) error {
+	if s.db == nil {
+		return errors.New("nil db in TwoFactorStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			UPDATE user_totp SET confirmed_at = NOW(), last_used_step = $2
+			WHERE user_id = $1 AND confirmed_at IS NULL
+		`
+		res, err := tx.ExecContext(ctx, query, userID, step)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		query = `DELETE FROM user_recovery_codes WHERE user_id = $1`
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+
+		query = `INSERT INTO user_recovery_codes (user_id, code) VALUES ($1, $2)`
+		for _, code := range recoveryCodes {
+			if _, err := tx.ExecContext(ctx, query, userID, hashToken(code)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UseCode saves the step of accepted code. ErrConflict means
+// that a code of the same or later step was already used
+func (s *TwoFactorStore) UseCode(ctx context.Context, userID int64, step int64) error {
+	if s.db == nil {
+		return errors.New("nil db in TwoFactorStore")
+	}
+
+	const query = `
+		UPDATE user_totp SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2 AND confirmed_at IS NOT NULL
+	`
+
+	res, err := s.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the code as used. ErrNotFound is returned
+// for unknown or already used codes
+func (s *TwoFactorStore) UseRecoveryCode(ctx context.Context, userID int64, code string) error {
+	if s.db == nil {
+		return errors.New("nil db in TwoFactorStore")
+	}
+
+	const query = `
+		UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code = $2 AND used_at IS NULL
+	`
+
+	res, err := s.db.ExecContext(ctx, query, userID, hashToken(code))
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *TwoFactorStore) Disable(ctx context.Context, userID int64) error {
+	if s.db == nil {
+		return errors.New("nil db in TwoFactorStore")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `DELETE FROM user_recovery_codes WHERE user_id = $1`
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+
+		query = `DELETE FROM user_totp WHERE user_id = $1`
+		res, err := tx.ExecContext(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
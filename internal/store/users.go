@@ -2,9 +2,7 @@ package store
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -29,6 +27,31 @@ type User struct {
 	IsActive  bool     `json:"is_active"`
 	RoleID    int64    `json:"role_id"`
 	Role      Role     `json:"role"`
+	// False for users created by OIDC login until the password is reset
+	HasPassword bool `json:"has_password"`
+	// Set if the user asked to delete the account
+	DeletedAt *string `json:"deleted_at,omitempty"`
+	// Set if the user is suspended by an admin
+	Suspension *Suspension `json:"suspension,omitempty"`
+	Profile
+	Counts
+}
+
+// Profile is the public info which user fills in
+type Profile struct {
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	AvatarURL   string `json:"avatar_url"`
+	Website     string `json:"website"`
+	// Posts of private user are visible only to approved followers
+	IsPrivate bool `json:"is_private"`
+}
+
+// Counts are maintained by DB triggers, so they are read without aggregation
+type Counts struct {
+	FollowersCount int64 `json:"followers_count"`
+	FollowingCount int64 `json:"following_count"`
+	PostsCount     int64 `json:"posts_count"`
 }
 
 type password struct {
@@ -80,23 +103,25 @@ func (u *UserStore) Create(ctx context.Context, tx *sql.Tx,
 		&user.CreatedAt,
 	)
 	if err != nil {
-		var pqErr *pq.Error
-		if errors.As(err, &pqErr) {
-			if pqErr.Code == "23505" { // 23505 is Unique Violation
-				switch pqErr.Constraint {
-				case "users_email_key":
-					return ErrDuplicateEmail
-				case "users_username_key":
-					return ErrDuplicateUsername
-				}
-			}
-		}
-		return err
+		return duplicateUserError(err)
 	}
 
 	return nil
 }
 
+// duplicateUserError maps unique violations of the users table to store errors
+func duplicateUserError(err error) error {
+	if pgErr, ok := pgError(err, pgUniqueViolation); ok {
+		switch pgErr.ConstraintName {
+		case "users_email_key":
+			return ErrDuplicateEmail
+		case "users_username_key":
+			return ErrDuplicateUsername
+		}
+	}
+	return err
+}
+
 func (u *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 	if u.db == nil {
 		return nil, errors.New("nil db in UserStore")
@@ -108,23 +133,57 @@ func (u *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
             email,
 			username,
 			password,
-			created_at,
-			roles.*
+			has_password,
+			users.created_at,
+			display_name,
+			bio,
+			avatar_url,
+			website,
+			is_private,
+			followers_count,
+			following_count,
+			posts_count,
+			roles.*,
+			s.id, s.admin_id, s.reason, s.expires_at, s.created_at
         FROM users
 		JOIN roles ON(users.role_id = roles.id)
-        WHERE users.id = $1 AND is_active = true;
+		LEFT JOIN LATERAL (
+			SELECT id, admin_id, reason, expires_at, created_at
+			FROM user_suspensions
+			WHERE user_id = users.id AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+			ORDER BY created_at DESC
+			LIMIT 1
+		) s ON true
+        WHERE users.id = $1 AND is_active = true AND deleted_at IS NULL;
 		`
 	var user User
+	var suspensionID sql.NullInt64
+	var suspension Suspension
+	var reason, suspendedAt sql.NullString
 	err := u.db.QueryRowContext(ctx, query, userID).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Username,
 		&user.Password.hash,
+		&user.HasPassword,
 		&user.CreatedAt,
+		&user.DisplayName,
+		&user.Bio,
+		&user.AvatarURL,
+		&user.Website,
+		&user.IsPrivate,
+		&user.FollowersCount,
+		&user.FollowingCount,
+		&user.PostsCount,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.Level,
 		&user.Role.Description,
+		&suspensionID,
+		&suspension.AdminID,
+		&reason,
+		&suspension.ExpiresAt,
+		&suspendedAt,
 	)
 	if err != nil {
 		switch {
@@ -136,6 +195,13 @@ func (u *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 	}
 	//Because only active user will be returned from DB
 	user.IsActive = true
+	if suspensionID.Valid {
+		suspension.ID = suspensionID.Int64
+		suspension.UserID = user.ID
+		suspension.Reason = reason.String
+		suspension.CreatedAt = suspendedAt.String
+		user.Suspension = &suspension
+	}
 
 	return &user, nil
 }
@@ -151,7 +217,8 @@ func (u *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
             email,
 			username,
 			password,
-			created_at
+			created_at,
+			deleted_at
         FROM users
         WHERE email = $1 AND is_active = true;
 		`
@@ -162,6 +229,7 @@ func (u *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 		&user.Username,
 		&user.Password.hash,
 		&user.CreatedAt,
+		&user.DeletedAt,
 	)
 	if err != nil {
 		switch {
@@ -217,6 +285,289 @@ func (u *UserStore) Activate(
 	})
 }
 
+// UpdateProfile stores public profile fields of the user
+func (u *UserStore) UpdateProfile(ctx context.Context, user *User) error {
+	if u.db == nil {
+		return errors.New("nil db in UserStore")
+	}
+
+	query := `
+		UPDATE users SET display_name = $1, bio = $2, avatar_url = $3, website = $4, is_private = $5
+		WHERE id = $6 AND is_active = true
+		`
+
+	res, err := u.db.ExecContext(ctx, query,
+		user.DisplayName,
+		user.Bio,
+		user.AvatarURL,
+		user.Website,
+		user.IsPrivate,
+		user.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// RenewInvitation replaces invitations of not activated user with the email
+// by a new one, so a lost or expired activation link can be sent again
+func (u *UserStore) RenewInvitation(
+	ctx context.Context,
+	email string,
+	token string,
+	invitationExp time.Duration) (*User, error) {
+
+	var user User
+	err := withTx(u.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT id, username, email, created_at
+			FROM users
+			WHERE email = $1 AND is_active = false
+			FOR UPDATE
+			`
+		err := tx.QueryRowContext(ctx, query, email).Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.CreatedAt,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if err := u.deleteUserInvitations(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		return u.createUserInvitation(ctx, tx, hashToken(token), invitationExp, user.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// DeleteExpiredInvitations removes invitations which can not be used anymore
+func (u *UserStore) DeleteExpiredInvitations(ctx context.Context) (int64, error) {
+	if u.db == nil {
+		return 0, errors.New("nil db in UserStore")
+	}
+
+	query := `DELETE FROM user_invitations WHERE expiry <= NOW()`
+
+	res, err := u.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// DeleteNotActivated removes users who were registered earlier than gracePeriod ago,
+// never activated the account and have no valid invitation
+func (u *UserStore) DeleteNotActivated(ctx context.Context, gracePeriod time.Duration) (int64, error) {
+	if u.db == nil {
+		return 0, errors.New("nil db in UserStore")
+	}
+
+	var deleted int64
+	err := withTx(u.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			DELETE FROM users u
+			WHERE u.is_active = false AND u.created_at < $1 AND NOT EXISTS (
+				SELECT 1 FROM user_invitations ui
+				WHERE ui.user_id = u.id AND ui.expiry > NOW())
+			RETURNING u.id
+			`
+		rows, err := tx.QueryContext(ctx, query, time.Now().Add(-gracePeriod))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		userIDs := []int64{}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			userIDs = append(userIDs, id)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		deleted = int64(len(userIDs))
+
+		//Invitations have no foreign key to users
+		invitationsQuery := `DELETE FROM user_invitations WHERE user_id = ANY($1)`
+		_, err = tx.ExecContext(ctx, invitationsQuery, pq.Array(userIDs))
+		return err
+	})
+
+	return deleted, err
+}
+
+// CreatePasswordReset stores a reset token for the user. Tokens created earlier
+// are removed, so only the last emailed link can be used
+func (u *UserStore) CreatePasswordReset(
+	ctx context.Context,
+	userID int64,
+	token string,
+	exp time.Duration) error {
+
+	return withTx(u.db, ctx, func(tx *sql.Tx) error {
+		if err := u.deletePasswordResets(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO password_resets (token, user_id, expiry) VALUES ($1, $2, $3)`
+
+		_, err := tx.ExecContext(ctx, query, hashToken(token), userID, time.Now().Add(exp))
+		return err
+	})
+}
+
+// ResetPassword sets the password of the user to user.Password if the reset
+// token is valid. The token is single-use and all sessions of the user
+// are revoked, so every device has to login again
+func (u *UserStore) ResetPassword(
+	ctx context.Context, token string, user *User) error {
+	return withTx(u.db, ctx, func(tx *sql.Tx) error {
+		//1. find user by token
+		query := `
+			SELECT u.id, u.username, u.email, u.created_at
+			FROM users u
+			JOIN password_resets pr ON u.id = pr.user_id
+			WHERE pr.token = $1 AND pr.expiry > $2 AND u.is_active = true
+			`
+		err := tx.QueryRowContext(ctx, query, hashToken(token), time.Now()).Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.CreatedAt,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+		user.IsActive = true
+
+		//2. update the password
+		if err := u.updatePassword(ctx, tx, user); err != nil {
+			return err
+		}
+		//3. token can be used only once
+		if err := u.deletePasswordResets(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		//4. logout everywhere
+		return revokeUserSessions(ctx, tx, user.ID)
+	})
+}
+
+// ChangePassword stores user.Password and revokes all sessions of the user
+func (u *UserStore) ChangePassword(ctx context.Context, user *User) error {
+	return withTx(u.db, ctx, func(tx *sql.Tx) error {
+		if err := u.updatePassword(ctx, tx, user); err != nil {
+			return err
+		}
+
+		return revokeUserSessions(ctx, tx, user.ID)
+	})
+}
+
+// CreateEmailChange stores a confirmation token for the new email.
+// The email of the user is changed only after ConfirmEmailChange
+func (u *UserStore) CreateEmailChange(
+	ctx context.Context,
+	userID int64,
+	email string,
+	token string,
+	exp time.Duration) error {
+
+	return withTx(u.db, ctx, func(tx *sql.Tx) error {
+		var exists bool
+		query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
+		if err := tx.QueryRowContext(ctx, query, email).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrDuplicateEmail
+		}
+
+		if err := u.deleteEmailChanges(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		query = `INSERT INTO user_email_changes (token, user_id, email, expiry) VALUES ($1, $2, $3, $4)`
+		_, err := tx.ExecContext(ctx, query, hashToken(token), userID, email, time.Now().Add(exp))
+		return err
+	})
+}
+
+// ConfirmEmailChange sets the email that was confirmed by token
+// and returns ID of the updated user
+func (u *UserStore) ConfirmEmailChange(ctx context.Context, token string) (int64, error) {
+	var userID int64
+	err := withTx(u.db, ctx, func(tx *sql.Tx) error {
+		//1. find the new email by token
+		var email string
+		query := `
+			SELECT user_id, email
+			FROM user_email_changes
+			WHERE token = $1 AND expiry > $2
+			`
+		err := tx.QueryRowContext(ctx, query, hashToken(token), time.Now()).Scan(
+			&userID,
+			&email,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		//2. update the user. Email could be taken after the token was created
+		query = `UPDATE users SET email = $1 WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, email, userID); err != nil {
+			if _, ok := pgError(err, pgUniqueViolation); ok {
+				return ErrDuplicateEmail
+			}
+			return err
+		}
+
+		//3. clean the tokens
+		return u.deleteEmailChanges(ctx, tx, userID)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
 func (u *UserStore) Delete(ctx context.Context, userID int64) error {
 	return withTx(u.db, ctx, func(tx *sql.Tx) error {
 		if err := u.delete(ctx, tx, userID); err != nil {
@@ -255,6 +606,28 @@ func (s *UserStore) deleteUserInvitations(ctx context.Context, tx *sql.Tx, userI
 	return nil
 }
 
+func (u *UserStore) deletePasswordResets(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `DELETE FROM password_resets WHERE user_id = $1`
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *UserStore) deleteEmailChanges(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `DELETE FROM user_email_changes WHERE user_id = $1`
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (u *UserStore) getUserFromUnvitation(
 	ctx context.Context, tx *sql.Tx,
 	token string) (User, error) {
@@ -264,11 +637,9 @@ func (u *UserStore) getUserFromUnvitation(
 		JOIN user_invitations ui ON u.id = ui.user_id
 		WHERE ui.token = $1 AND ui.expiry > $2
 		`
-	hash := sha256.Sum256([]byte(token))
-	hashToken := hex.EncodeToString(hash[:])
 	user := User{}
 
-	err := tx.QueryRowContext(ctx, query, hashToken, time.Now()).Scan(
+	err := tx.QueryRowContext(ctx, query, hashToken(token), time.Now()).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
@@ -313,3 +684,16 @@ func (u *UserStore) update(
 
 	return nil
 }
+
+func (u *UserStore) updatePassword(
+	ctx context.Context, tx *sql.Tx, user *User) error {
+
+	query := `UPDATE users SET password = $1, has_password = true WHERE id = $2`
+
+	_, err := tx.ExecContext(ctx, query, user.Password.hash, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestDuplicateUserError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{&pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "users_email_key"}, ErrDuplicateEmail},
+		{&pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "users_username_key"}, ErrDuplicateUsername},
+		{&pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "other_key"}, nil},
+	}
+
+	for _, tt := range tests {
+		got := duplicateUserError(tt.err)
+		want := tt.want
+		if want == nil {
+			want = tt.err
+		}
+		if !errors.Is(got, want) {
+			t.Errorf("for %v expected %v got %v", tt.err, want, got)
+		}
+	}
+}